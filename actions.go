@@ -0,0 +1,367 @@
+package list
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Names of the actions, as printed by --dry-run.
+const (
+	ActMove     = "move"
+	ActCopy     = "copy"
+	ActSymlink  = "symlink"
+	ActHardlink = "hardlink"
+	ActTrash    = "trash"
+	ActDelete   = "delete"
+	ActRename   = "rename"
+)
+
+// actionDone is the past tense of each action, used in the summary.
+var actionDone = map[string]string{
+	ActMove:     "moved",
+	ActCopy:     "copied",
+	ActSymlink:  "symlinked",
+	ActHardlink: "hardlinked",
+	ActTrash:    "trashed",
+	ActDelete:   "deleted",
+	ActRename:   "renamed",
+}
+
+// Op is a single file operation of an action. To is empty for trash and delete.
+type Op struct {
+	Action string
+	From   string
+	To     string
+	fi     *Finfo
+	after  fs.FileInfo // destination right after the operation, see RecordJournal
+	aside  string      // where the file overwritten at the destination was moved, so undo can restore it
+}
+
+// String formats the operation like a command line, quoted for the shell.
+func (op Op) String() string {
+	args := []string{op.Action, op.From}
+	if op.To != "" {
+		args = append(args, op.To)
+	}
+	return QuoteCommand(args)
+}
+
+// HasAction reports whether an action is used instead of printing the result.
+func HasAction(opts *Options) bool {
+	name, _ := SelectedAction(opts)
+	return name != ""
+}
+
+// SelectedAction returns the action given in the options and its destination directory, if any.
+// Only one action can be used at a time.
+func SelectedAction(opts *Options) (name, dest string) {
+	actions := []struct {
+		flag, name, dest string
+		on               bool
+	}{
+		{"--move-to", ActMove, opts.MoveTo, opts.MoveTo != ""},
+		{"--copy-to", ActCopy, opts.CopyTo, opts.CopyTo != ""},
+		{"--symlink-to", ActSymlink, opts.SymlinkTo, opts.SymlinkTo != ""},
+		{"--hardlink-to", ActHardlink, opts.HardlinkTo, opts.HardlinkTo != ""},
+		{"--trash", ActTrash, "", opts.Trash},
+		{"--delete", ActDelete, "", opts.Delete},
+		{"--rename", ActRename, "", opts.Rename},
+		{"--rename-pattern", ActRename, "", opts.RenamePattern != ""},
+	}
+
+	var flag string
+	for _, a := range actions {
+		if !a.on {
+			continue
+		}
+		if flag != "" {
+			log.Fatalf("%s and %s can not be used together", flag, a.flag)
+		}
+		flag, name, dest = a.flag, a.name, a.dest
+	}
+	return
+}
+
+// PlanActions returns the operations of the selected action on the result, resolving conflicts
+// with existing files by --conflict. Files beneath a directory in the result are left to it.
+// The number of files which can not be acted on is returned along with the operations.
+func PlanActions(res *Result, opts *Options) (ops []Op, skipped, failed int) {
+	name, dest := SelectedAction(opts)
+	roots := treeRoots(opts)
+	taken := map[string]bool{}
+
+	for _, fi := range dropNested(res.Files) {
+		op := Op{Action: name, From: fi.Path, fi: fi}
+		if fi.Archive != "" && name != ActCopy {
+			slog.Error("archive entries can only be copied", "path", fi.Path)
+			failed++
+			continue
+		}
+		if dest == "" {
+			ops = append(ops, op)
+			continue
+		}
+
+		op.To = filepath.Join(dest, actionRel(fi, roots, opts))
+		if within(op.To, fi.Path) {
+			slog.Error("destination is within the file", "path", fi.Path, "destination", op.To)
+			failed++
+			continue
+		}
+
+		// overwriting only applies to files which existed before, never to another file of the result
+		if taken[op.To] {
+			if opts.Conflict != "rename" {
+				slog.Error("another file has the same destination, skipping", "path", fi.Path, "destination", op.To)
+				skipped++
+				continue
+			}
+			op.To = freeName(op.To, func(p string) bool { return exists(p) || taken[p] })
+		}
+
+		if exists(op.To) {
+			switch opts.Conflict {
+			case "overwrite":
+			case "rename":
+				op.To = freeName(op.To, func(p string) bool { return exists(p) || taken[p] })
+			default:
+				slog.Debug("destination exists, skipping", "path", fi.Path, "destination", op.To)
+				skipped++
+				continue
+			}
+		}
+		taken[op.To] = true
+		ops = append(ops, op)
+	}
+	return
+}
+
+// dropNested drops the files which are beneath a directory or archive also in the files.
+func dropNested(files []*Finfo) []*Finfo {
+	paths := make(map[string]bool, len(files))
+	for _, fi := range files {
+		paths[filepath.Clean(fi.Path)] = true
+	}
+
+	res := make([]*Finfo, 0, len(files))
+	for _, fi := range files {
+		nested := false
+		for dir := filepath.Dir(filepath.Clean(fi.Path)); ; {
+			if paths[dir] {
+				nested = true
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+		if !nested {
+			res = append(res, fi)
+		}
+	}
+	return res
+}
+
+// actionRel returns the path of the file relative to the root it was found in, to preserve
+// the structure beneath the roots in the destination. With --flat only its name is used.
+func actionRel(fi *Finfo, roots []string, opts *Options) string {
+	if opts.Flat {
+		return fi.Name
+	}
+	fp := filepath.ToSlash(filepath.Clean(fi.Path))
+	if opts.Absolute {
+		if abs, err := filepath.Abs(fi.Path); err == nil {
+			fp = filepath.ToSlash(abs)
+		}
+	}
+	for _, root := range roots {
+		if rel, ok := underRoot(fp, root); ok && rel != "" && rel != "." {
+			return filepath.FromSlash(rel)
+		}
+	}
+	return fi.Name
+}
+
+// within reports whether path is the same as or beneath dir.
+func within(path, dir string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// freeName returns the first path of the form `name (n).ext` which is not taken.
+func freeName(path string, taken func(string) bool) string {
+	ext := filepath.Ext(path)
+	if ext == filepath.Base(path) {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(path, ext)
+	for n := 1; ; n++ {
+		candidate := stem + " (" + strconv.Itoa(n) + ")" + ext
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// Act runs the selected action on the result, or prints its operations with --dry-run.
+// A progress line is written to stderr when it is a terminal, followed by a summary.
+// It returns the exit status to exit with, 1 if any file failed.
+func Act(res *Result, opts *Options) int {
+	switch {
+	case opts.RenamePattern != "":
+		return RenameByPattern(res, opts)
+	case opts.Rename:
+		return EditRenames(res, opts)
+	}
+
+	ops, skipped, failed := PlanActions(res, opts)
+
+	if opts.DryRun {
+		for _, op := range ops {
+			fmt.Println(op.String())
+		}
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	done := ApplyOps(ops, opts)
+	failed += len(ops) - len(done)
+
+	if !opts.Quiet {
+		name, _ := SelectedAction(opts)
+		fmt.Fprintf(os.Stderr, "%d %s, %d skipped, %d failed\n", len(done), actionDone[name], skipped, failed)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// ApplyOps runs the operations, returning the ones which succeeded. Errors are logged and do not stop the others.
+// The operations which succeeded are recorded in a journal, to be undone with --undo.
+func ApplyOps(ops []Op, opts *Options) (done []Op) {
+	p := newProgress(len(ops), opts)
+	defer p.clear()
+
+	for _, op := range ops {
+		p.step(op)
+		if err := applyOp(&op, opts); err != nil {
+			p.clear()
+			slog.Error("error running action", "action", op.Action, "path", op.From, "error", err)
+			continue
+		}
+		if op.To != "" {
+			op.after, _ = os.Lstat(op.To)
+		}
+		done = append(done, op)
+	}
+	RecordJournal(done)
+	return
+}
+
+// applyOp runs the operation, setting its destination in the trash for trash. A file overwritten
+// at the destination is moved aside into the journal directory, and back if the operation fails.
+func applyOp(op *Op, opts *Options) error {
+	if op.To != "" {
+		if exists(op.To) && !sameFile(op.From, op.To) {
+			if opts.Conflict != "overwrite" {
+				return fmt.Errorf("destination %q exists", op.To)
+			}
+			aside, err := moveAside(op.To)
+			if err != nil {
+				return err
+			}
+			op.aside = aside
+		}
+		if err := os.MkdirAll(filepath.Dir(op.To), 0o755); err != nil {
+			return err
+		}
+	}
+
+	err := runOp(op)
+	if err != nil && op.aside != "" && !exists(op.To) {
+		if MovePath(op.aside, op.To) == nil {
+			os.Remove(filepath.Dir(op.aside))
+			op.aside = ""
+		}
+	}
+	return err
+}
+
+func runOp(op *Op) error {
+	switch op.Action {
+	case ActMove, ActRename:
+		return MovePath(op.From, op.To)
+	case ActCopy:
+		return CopyFinfo(op.fi, op.To)
+	case ActSymlink:
+		abs, err := filepath.Abs(op.From)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(abs, op.To)
+	case ActHardlink:
+		return HardlinkPath(op.From, op.To)
+	case ActTrash:
+		to, err := TrashPath(op.From)
+		op.To = to
+		return err
+	case ActDelete:
+		return os.RemoveAll(op.From)
+	}
+	return fmt.Errorf("unknown action %q", op.Action)
+}
+
+// progress writes a progress line of the operations to stderr, when it is a terminal.
+type progress struct {
+	total, n int
+	on       bool
+}
+
+func newProgress(total int, opts *Options) *progress {
+	p := &progress{total: total}
+	if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		p.on = !opts.Quiet
+	}
+	return p
+}
+
+func (p *progress) step(op Op) {
+	p.n++
+	if !p.on {
+		return
+	}
+	path := op.From
+	if len(path) > 60 {
+		path = "..." + path[len(path)-57:]
+	}
+	fmt.Fprintf(os.Stderr, "\r\x1b[K%s %d/%d %s", op.Action, p.n, p.total, path)
+}
+
+func (p *progress) clear() {
+	if p.on {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+	}
+}
@@ -0,0 +1,99 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPlanActionsConflicts(t *testing.T) {
+	tests := []struct {
+		conflict string
+		want     []string // destinations relative to out
+		skipped  int
+	}{
+		{"skip", nil, 3},
+		{"overwrite", []string{"x.txt", "y.txt"}, 1},
+		{"rename", []string{"x (1).txt", "x (2).txt", "y (1).txt"}, 0},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"src/a/x.txt": "AAA", "src/b/x.txt": "BBB", "src/y.txt": "Y", "out/x.txt": "OLD", "out/y.txt": "OLD"})
+		out := filepath.Join(dir, "out")
+
+		opts := &Options{}
+		opts.MoveTo = out
+		opts.Flat = true
+		opts.Conflict = tt.conflict
+		res := &Result{Files: []*Finfo{
+			{Name: "x.txt", Path: filepath.Join(dir, "src/a/x.txt")},
+			{Name: "x.txt", Path: filepath.Join(dir, "src/b/x.txt")},
+			{Name: "y.txt", Path: filepath.Join(dir, "src/y.txt")},
+		}}
+
+		ops, skipped, failed := PlanActions(res, opts)
+		if skipped != tt.skipped || failed != 0 {
+			t.Errorf("%s: got %d skipped %d failed, want %d skipped", tt.conflict, skipped, failed, tt.skipped)
+		}
+		var got []string
+		for _, op := range ops {
+			rel, _ := filepath.Rel(out, op.To)
+			got = append(got, rel)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got destinations %q, want %q", tt.conflict, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got destinations %q, want %q", tt.conflict, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestActOverwriteKeepsBothCollidingFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"src/a/x.txt": "AAA", "src/b/x.txt": "BBB"})
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	opts := &Options{}
+	opts.MoveTo = filepath.Join(dir, "out")
+	opts.Flat = true
+	opts.Conflict = "overwrite"
+	opts.Quiet = true
+	res := &Result{Files: []*Finfo{
+		{Name: "x.txt", Path: filepath.Join(dir, "src/a/x.txt")},
+		{Name: "x.txt", Path: filepath.Join(dir, "src/b/x.txt")},
+	}}
+	Act(res, opts)
+
+	if got := readFile(t, filepath.Join(dir, "out/x.txt")); got != "AAA" {
+		t.Errorf("out/x.txt: got %q, want AAA", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "src/b/x.txt")); got != "BBB" {
+		t.Errorf("src/b/x.txt: got %q, want it left in place", got)
+	}
+}
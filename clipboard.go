@@ -0,0 +1,122 @@
+package list
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ClipboardTools are the commands tried in order, see Copy.
+var ClipboardTools = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"pbcopy"},
+	{"clip.exe"},
+}
+
+// ClipboardText returns what --clipboard copies: the paths, absolute paths, or the output without colors.
+func ClipboardText(els []*Finfo, opts *Options) string {
+	var sb strings.Builder
+	switch opts.Clipboard {
+	case "output":
+		o := *opts
+		o.Color = "never"
+		w := bufio.NewWriter(&sb)
+		WriteResult(w, els, &o)
+		w.Flush()
+	case "absolute":
+		for _, fi := range els {
+			fp, _ := filepath.Abs(fi.Path)
+			sb.WriteString(filepath.ToSlash(fp) + Terminator(opts))
+		}
+	default:
+		for _, fi := range els {
+			sb.WriteString(FormatPath(fi, opts) + Terminator(opts))
+		}
+	}
+	return sb.String()
+}
+
+// CopyResult copies the files to the clipboard, see ClipboardText.
+func CopyResult(els []*Finfo, opts *Options) error {
+	return Copy(ClipboardText(els, opts))
+}
+
+// OSC52Max is the longest OSC 52 sequence written, as many terminals drop longer ones.
+const OSC52Max = 100000
+
+// Copy copies the text to the clipboard. In a local graphical session a clipboard tool is used,
+// and an OSC 52 escape sequence written to the terminal, which works over SSH and in tmux, is the
+// fallback. Otherwise, e.g., over SSH, the escape sequence is tried first.
+func Copy(text string) error {
+	methods := []func(string) error{CopyOSC52, CopyTool}
+	if preferTool() {
+		methods = []func(string) error{CopyTool, CopyOSC52}
+	}
+
+	var errs []error
+	for _, copy := range methods {
+		err := copy(text)
+		if err == nil {
+			return nil
+		}
+		slog.Debug("could not copy", "error", err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// preferTool reports whether the session is a local graphical one, whose clipboard the tools can reach.
+// The terminal may not support OSC 52 in such sessions, and writing to it would silently do nothing.
+func preferTool() bool {
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		return false
+	}
+	return os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("DISPLAY") != "" ||
+		runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+}
+
+// CopyTool copies the text with the first of ClipboardTools which is installed and succeeds.
+func CopyTool(text string) error {
+	for _, tool := range ClipboardTools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			slog.Debug("clipboard tool failed", "tool", tool[0], "error", err)
+			continue
+		}
+		return nil
+	}
+	return errors.New("no clipboard tool available")
+}
+
+// CopyOSC52 writes the OSC 52 sequence to the controlling terminal, wrapping it for tmux when inside one.
+// Texts whose sequence would be longer than OSC52Max are not written.
+func CopyOSC52(text string) error {
+	seq := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	if len(seq) > OSC52Max {
+		return fmt.Errorf("%d bytes is too long to copy with OSC 52", len(text))
+	}
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	_, err = tty.WriteString(seq)
+	return err
+}
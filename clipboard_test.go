@@ -0,0 +1,33 @@
+package list
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreferTool(t *testing.T) {
+	tests := []struct {
+		wayland, display, ssh string
+		want                  bool
+	}{
+		{"wayland-0", "", "", true},
+		{"", ":0", "", true},
+		{"", ":0", "/dev/pts/1", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("WAYLAND_DISPLAY", tt.wayland)
+		t.Setenv("DISPLAY", tt.display)
+		t.Setenv("SSH_TTY", tt.ssh)
+		t.Setenv("SSH_CONNECTION", "")
+		if got := preferTool(); got != tt.want {
+			t.Errorf("wayland=%q display=%q ssh=%q: got %v, want %v", tt.wayland, tt.display, tt.ssh, got, tt.want)
+		}
+	}
+}
+
+func TestCopyOSC52TooLong(t *testing.T) {
+	err := CopyOSC52(strings.Repeat("x", OSC52Max))
+	if err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("got %v, want a too long error", err)
+	}
+}
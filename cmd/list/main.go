@@ -1,26 +1,68 @@
-package main
-
-import (
-	"os"
-
-	"github.com/periaate/common"
-	"github.com/periaate/list"
-)
-
-func main() {
-	opts := list.Parse(os.Args[1:])
-
-	pipedValues := common.ReadPipe()
-	if len(pipedValues) != 0 {
-		opts.Args = append(opts.Args, pipedValues...)
-	}
-
-	res := list.Run(opts)
-
-	if opts.ExecArgs != nil || len(opts.ExecArgs) != 0 {
-		list.Exec(res, opts)
-		return
-	}
-
-	list.PrintWithBuf(res.Files, opts)
-}
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/periaate/list"
+	"github.com/periaate/list/internal/pipe"
+)
+
+func main() {
+	opts := list.Parse(os.Args[1:])
+
+	switch {
+	case opts.Undo != "":
+		os.Exit(list.Undo(opts))
+	case opts.ShowJournal:
+		os.Exit(list.PrintJournals())
+	case opts.Index != "":
+		os.Exit(list.RunIndex(opts))
+	}
+
+	pipedValues := pipe.Read(opts.NullInput)
+	if len(pipedValues) != 0 {
+		opts.Args = append(opts.Args, pipedValues...)
+	}
+
+	if opts.Watch != "" {
+		os.Exit(list.Watch(opts, func(res *list.Result) int { return output(res, opts) }))
+	}
+
+	var res *list.Result
+	if opts.Pick {
+		var status int
+		if res, status = list.Pick(opts); status != 0 {
+			os.Exit(status)
+		}
+	} else {
+		res = list.Run(opts)
+	}
+
+	os.Exit(output(res, opts))
+}
+
+// output verifies, acts on, runs the command on, or prints the result, returning the exit status.
+func output(res *list.Result, opts *list.Options) int {
+	if opts.Verify != "" {
+		ok, err := list.Verify(res, opts, os.Stdout)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if !ok {
+			return 1
+		}
+		return 0
+	}
+
+	if list.HasAction(opts) {
+		return list.Act(res, opts)
+	}
+
+	if opts.ExecArgs != nil || len(opts.ExecArgs) != 0 {
+		return list.Exec(res, opts)
+	}
+
+	list.PrintWithBuf(res.Files, opts)
+	return 0
+}
@@ -1,30 +1,45 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"os"
-
-	"github.com/periaate/common"
-	"github.com/periaate/slice"
-)
-
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatalln("No slice expression given\nUsage:\tslice [PATTERN]")
-	}
-	arg := os.Args[1]
-
-	vals := common.ReadPipe()
-	expr := slice.NewExpression[string]()
-	expr.Parse(arg)
-
-	res, err := expr.Eval(vals)
-	if err != nil {
-		log.Fatalln("error during slicing", err)
-	}
-
-	for _, s := range res {
-		fmt.Println(s)
-	}
-}
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+
+	"github.com/periaate/list/internal/pipe"
+	"github.com/periaate/slice"
+)
+
+func main() {
+	args := os.Args[1:]
+
+	// -0 separates both the input and output with NUL, like list --null-input --print0
+	null := len(args) > 0 && args[0] == "-0"
+	if null {
+		args = args[1:]
+	}
+
+	if len(args) < 1 {
+		log.Fatalln("No slice expression given\nUsage:\tslice [-0] [PATTERN]")
+	}
+	arg := args[0]
+
+	vals := pipe.Read(null)
+	expr := slice.NewExpression[string]()
+	expr.Parse(arg)
+
+	res, err := expr.Eval(vals)
+	if err != nil {
+		log.Fatalln("error during slicing", err)
+	}
+
+	end := "\n"
+	if null {
+		end = "\x00"
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, s := range res {
+		w.WriteString(s + end)
+	}
+	w.Flush()
+}
@@ -0,0 +1,144 @@
+package list
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// KindColors are the SGR sequences of kinds which LS_COLORS has no color for.
+// User defined kinds can set their own with the color field.
+var KindColors = map[string]string{
+	Image:    "35",
+	Video:    "01;35",
+	Audio:    "36",
+	Archive:  "01;31",
+	Code:     "32",
+	Conf:     "33",
+	Docs:     "37",
+	OtherDev: "32",
+}
+
+// defaultColors are used for the file types LS_COLORS does not set, matching the defaults of dircolors.
+var defaultColors = map[string]string{
+	"di": "01;34",
+	"ln": "01;36",
+	"ex": "01;32",
+	"pi": "33",
+	"so": "01;35",
+	"bd": "01;33",
+	"cd": "01;33",
+}
+
+// Colors colors paths by their file type, extension and kind.
+// Its methods do nothing on a nil receiver, i.e., when colors are disabled.
+type Colors struct {
+	types    map[string]string // LS_COLORS file type keys, e.g., di
+	suffixes map[string]string // LS_COLORS glob keys, e.g., *.jpg as .jpg
+}
+
+// NewColors returns the colors to use with --color, or nil if paths should not be colored.
+// In auto mode colors are used when stdout is a terminal and NO_COLOR is not set.
+func NewColors(opts *Options) *Colors {
+	switch opts.Color {
+	case "never":
+		return nil
+	case "always":
+	default:
+		if os.Getenv("NO_COLOR") != "" || opts.Print0 {
+			return nil
+		}
+		if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return nil
+		}
+	}
+	return ParseLsColors(os.Getenv("LS_COLORS"))
+}
+
+// ParseLsColors parses the LS_COLORS format, e.g., `di=01;34:*.jpg=01;35`.
+func ParseLsColors(env string) *Colors {
+	c := &Colors{types: map[string]string{}, suffixes: map[string]string{}}
+	for k, v := range defaultColors {
+		c.types[k] = v
+	}
+
+	for _, entry := range strings.Split(env, ":") {
+		key, seq, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(key, "*"); ok {
+			c.suffixes[strings.ToLower(suffix)] = seq
+			continue
+		}
+		c.types[key] = seq
+	}
+	return c
+}
+
+// Sequence returns the SGR sequence of the file, or an empty string if it is not colored.
+func (c *Colors) Sequence(fi *Finfo) string {
+	if c == nil {
+		return ""
+	}
+
+	var mode fs.FileMode
+	if fi.Info != nil {
+		mode = fi.Info.Mode()
+	} else if fi.IsDir {
+		mode = fs.ModeDir
+	}
+
+	switch {
+	case mode&fs.ModeDir != 0:
+		return c.types["di"]
+	case mode&fs.ModeSymlink != 0:
+		return c.types["ln"]
+	case mode&fs.ModeNamedPipe != 0:
+		return c.types["pi"]
+	case mode&fs.ModeSocket != 0:
+		return c.types["so"]
+	case mode&fs.ModeCharDevice != 0:
+		return c.types["cd"]
+	case mode&fs.ModeDevice != 0:
+		return c.types["bd"]
+	}
+
+	name := strings.ToLower(fi.Name)
+	var longest string
+	for suffix := range c.suffixes {
+		if len(suffix) > len(longest) && strings.HasSuffix(name, suffix) {
+			longest = suffix
+		}
+	}
+	if longest != "" {
+		return c.suffixes[longest]
+	}
+
+	if mode.IsRegular() && mode&0o111 != 0 {
+		return c.types["ex"]
+	}
+
+	for i := len(Kinds) - 1; i >= 0; i-- {
+		k := Kinds[i]
+		if !fi.Mask.Has(k.bit) {
+			continue
+		}
+		if k.Color != "" {
+			return k.Color
+		}
+		if seq, ok := KindColors[k.Name]; ok {
+			return seq
+		}
+	}
+	return c.types["fi"]
+}
+
+// Paint wraps s in the color of the file.
+func (c *Colors) Paint(s string, fi *Finfo) string {
+	seq := c.Sequence(fi)
+	if seq == "" || seq == "0" || seq == "00" {
+		return s
+	}
+	return "\x1b[" + seq + "m" + s + "\x1b[0m"
+}
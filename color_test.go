@@ -0,0 +1,39 @@
+package list
+
+import (
+	"testing"
+)
+
+func TestColorsSequence(t *testing.T) {
+	c := ParseLsColors("di=01;94:fi=0:*.JPG=01;33:*.tar.gz=31:*.gz=01;31:ex=32:broken:=")
+	tests := []struct {
+		fi   *Finfo
+		want string
+	}{
+		{&Finfo{Name: "d", IsDir: true}, "01;94"},
+		{&Finfo{Name: "a.jpg", Mask: MaskImage}, "01;33"},
+		{&Finfo{Name: "a.tar.gz", Mask: MaskArchive}, "31"},
+		{&Finfo{Name: "a.gz", Mask: MaskArchive}, "01;31"},
+		{&Finfo{Name: "a.png", Mask: MaskImage}, KindColors[Image]},
+		{&Finfo{Name: "a.zip", Mask: ExtMask("a.zip")}, KindColors[Archive]},
+		{&Finfo{Name: "plain"}, "0"},
+	}
+	for _, tt := range tests {
+		if got := c.Sequence(tt.fi); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.fi.Name, got, tt.want)
+		}
+	}
+
+	// file types LS_COLORS does not set use the defaults of dircolors
+	if got := ParseLsColors("").Sequence(&Finfo{Name: "d", IsDir: true}); got != defaultColors["di"] {
+		t.Errorf("got %q for a directory without LS_COLORS, want %q", got, defaultColors["di"])
+	}
+
+	var none *Colors
+	if got := none.Paint("a", &Finfo{Name: "a", IsDir: true}); got != "a" {
+		t.Errorf("nil colors painted %q", got)
+	}
+	if got := c.Paint("a", &Finfo{Name: "a", IsDir: true}); got != "\x1b[01;94ma\x1b[0m" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,213 @@
+package list
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// RcName is the name of the per-directory configuration files, discovered upwards from the working directory.
+const RcName = ".listrc"
+
+// Config sets default flags and named profiles, and defines kinds. See ExpandArgs for how they are merged.
+type Config struct {
+	Defaults []string            `json:"defaults,omitempty"`
+	Profiles map[string][]string `json:"profiles,omitempty"`
+	Quick    map[string][]string `json:"quick,omitempty"`
+	Kinds    []*Kind             `json:"kinds,omitempty"`
+}
+
+// ConfigPath returns the path of the user configuration, `$XDG_CONFIG_HOME/list/config.json` on Unix.
+func ConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "list", "config.json")
+}
+
+// ConfigPaths returns the user configuration followed by every RcName file from the
+// root down to the working directory, i.e., in the order of increasing precedence.
+func ConfigPaths() []string {
+	var rcs []string
+	dir, err := os.Getwd()
+	for err == nil {
+		rcs = append(rcs, filepath.Join(dir, RcName))
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	slices.Reverse(rcs)
+	return append([]string{ConfigPath()}, rcs...)
+}
+
+// LoadConfig reads and merges the configuration files, skipping those which do not exist.
+// Defaults are concatenated, while profiles and kinds of later files override earlier ones.
+func LoadConfig(paths ...string) (*Config, error) {
+	cfg := &Config{Profiles: map[string][]string{}, Quick: map[string][]string{}}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return cfg, err
+		}
+
+		var c Config
+		if err := json.Unmarshal(b, &c); err != nil {
+			return cfg, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		slog.Debug("loaded config", "path", path)
+		if filepath.Base(path) == RcName {
+			c.restrict(path)
+		}
+
+		cfg.Defaults = append(cfg.Defaults, c.Defaults...)
+		for name, args := range c.Profiles {
+			cfg.Profiles[name] = args
+		}
+		for key, flags := range c.Quick {
+			cfg.Quick[key] = flags
+		}
+		cfg.Kinds = append(cfg.Kinds, c.Kinds...)
+	}
+	return cfg, nil
+}
+
+// rcFlags are the flags RcName files may use, mapped to whether they take a value. Such files can come
+// from anyone, e.g., with a cloned repository, so they may only change traversal, filtering, processing and printing.
+var rcFlags = sync.OnceValue(func() map[string]bool {
+	flags := map[string]bool{}
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(ListingOpts{}),
+		reflect.TypeOf(FilterOpts{}),
+		reflect.TypeOf(ProcessOpts{}),
+		reflect.TypeOf(Printing{}),
+	} {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			takesValue := f.Type.Kind() != reflect.Bool
+			if long := f.Tag.Get("long"); long != "" {
+				flags["--"+long] = takesValue
+			}
+			if short := f.Tag.Get("short"); short != "" {
+				flags["-"+short] = takesValue
+			}
+		}
+	}
+	delete(flags, "--clipboard")
+	delete(flags, "-c")
+	return flags
+})
+
+// CheckRcArgs returns an error if the arguments use a flag which RcName files may not use, see rcFlags.
+func CheckRcArgs(args []string) error {
+	flags := rcFlags()
+	for _, arg := range args {
+		switch {
+		case arg == "--":
+			return nil
+		case arg == "::":
+			return errors.New("commands can not be run")
+		case len(arg) < 2 || arg[0] != '-':
+			continue
+		case strings.HasPrefix(arg, "--"):
+			name, _, _ := strings.Cut(arg, "=")
+			if _, ok := flags[name]; !ok {
+				return fmt.Errorf("%s is not allowed", name)
+			}
+		default:
+			for _, r := range arg[1:] {
+				takesValue, ok := flags["-"+string(r)]
+				if !ok {
+					return fmt.Errorf("-%c is not allowed", r)
+				}
+				if takesValue {
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// restrict drops the defaults, profiles and quick commands of an RcName file which use flags it may not use.
+func (c *Config) restrict(path string) {
+	if err := CheckRcArgs(c.Defaults); err != nil {
+		slog.Error("ignoring defaults of "+RcName, "path", path, "error", err)
+		c.Defaults = nil
+	}
+	for name, args := range c.Profiles {
+		if err := CheckRcArgs(args); err != nil {
+			slog.Error("ignoring profile of "+RcName, "path", path, "profile", name, "error", err)
+			delete(c.Profiles, name)
+		}
+	}
+	for key, flags := range c.Quick {
+		if err := CheckRcArgs(flags); err != nil {
+			slog.Error("ignoring quick command of "+RcName, "path", path, "key", key, "error", err)
+			delete(c.Quick, key)
+		}
+	}
+}
+
+// ExpandArgs replaces `@name` arguments with the flags of the named profile, and prepends the defaults.
+// Values of flags and arguments after `--` are not expanded.
+// The precedence, from lowest to highest, is: defaults of the user configuration, defaults of
+// RcName files from the outermost to the innermost, profiles in the order given, and command-line flags.
+// Flags which take a single value use the last one given, flags which can be repeated accumulate.
+func (c *Config) ExpandArgs(args []string) []string {
+	res := append([]string{}, c.Defaults...)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			// everything after is an argument, like with ExpandQuick
+			return append(append(res, rest...), args[i:]...)
+		case takesValue(arg) && i+1 < len(args):
+			rest = append(rest, arg, args[i+1])
+			i++
+			continue
+		case len(arg) > 1 && arg[0] == '@':
+			if profile, ok := c.Profiles[arg[1:]]; ok {
+				res = append(res, profile...)
+				continue
+			}
+			slog.Debug("no profile found, using as argument", "arg", arg)
+		}
+		rest = append(rest, arg)
+	}
+	return append(res, rest...)
+}
+
+// applyConfig loads the configuration and expands args with it, unless args contain --no-config.
+func applyConfig(args []string) []string {
+	if slices.Contains(args, "--no-config") {
+		return args
+	}
+
+	cfg, err := LoadConfig(ConfigPaths()...)
+	if err != nil {
+		slog.Error("error loading config", "error", err)
+	}
+	RegisterKinds(cfg.Kinds)
+	for key, flags := range cfg.Quick {
+		RegisterQuick(key, flags...)
+	}
+	return cfg.ExpandArgs(args)
+}
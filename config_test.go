@@ -0,0 +1,86 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckRcArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-I", ".git", "-r"}, true},
+		{[]string{"-rz", "--sort=size", "-H"}, true},
+		{[]string{"-s", "foo", "--contains", "bar"}, true},
+		{[]string{"-sfoo"}, true},
+		{[]string{"~query", "[:10]"}, true},
+		{[]string{"--", "--delete"}, true},
+		{[]string{"--trash"}, false},
+		{[]string{"--dry-run", "--delete"}, false},
+		{[]string{"--move-to=out"}, false},
+		{[]string{"-r", "::", "rm"}, false},
+		{[]string{"--index", "build"}, false},
+		{[]string{"-c"}, false},
+		{[]string{"-rl"}, false},
+	}
+	for _, tt := range tests {
+		if err := CheckRcArgs(tt.args); (err == nil) != tt.ok {
+			t.Errorf("%q: got %v, want ok=%v", tt.args, err, tt.ok)
+		}
+	}
+}
+
+func TestLoadConfigRestrictsRc(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "config.json")
+	rc := filepath.Join(dir, RcName)
+	if err := os.WriteFile(user, []byte(`{"defaults":["--dry-run"],"profiles":{"clean":["--trash"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rc, []byte(`{"defaults":["--trash","--dry-run"],"profiles":{"big":["-S","size"],"rm":["--delete"]},"quick":{"x":["--delete"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(user, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.Defaults, []string{"--dry-run"}) {
+		t.Errorf("defaults: got %q", cfg.Defaults)
+	}
+	if _, ok := cfg.Profiles["clean"]; !ok {
+		t.Errorf("profile of the user configuration was dropped")
+	}
+	if _, ok := cfg.Profiles["big"]; !ok {
+		t.Errorf("allowed profile of %s was dropped", RcName)
+	}
+	if _, ok := cfg.Profiles["rm"]; ok {
+		t.Errorf("profile with --delete of %s was kept", RcName)
+	}
+	if _, ok := cfg.Quick["x"]; ok {
+		t.Errorf("quick command with --delete of %s was kept", RcName)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	cfg := &Config{
+		Defaults: []string{"-I", ".git"},
+		Profiles: map[string][]string{"comics": {"-rz", "-i", "image"}},
+	}
+	tests := []struct {
+		args, want []string
+	}{
+		{[]string{"@comics", "~batman", "@missing"}, []string{"-I", ".git", "-rz", "-i", "image", "~batman", "@missing"}},
+		{[]string{"-s", "@comics", "@comics"}, []string{"-I", ".git", "-rz", "-i", "image", "-s", "@comics"}},
+		{[]string{"--search=x", "-rs", "@comics"}, []string{"-I", ".git", "--search=x", "-rs", "@comics"}},
+		{[]string{"dir", "--", "@comics"}, []string{"-I", ".git", "dir", "--", "@comics"}},
+	}
+	for _, tt := range tests {
+		if got := cfg.ExpandArgs(tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"regexp"
+)
+
+// probeLen is the amount of bytes inspected when deciding whether a file is binary.
+const probeLen = 8000
+
+type Matcher func(line []byte) bool
+
+// GetMatcher returns a matcher of the --contains patterns, or an error if any of them is not a valid --regex.
+func GetMatcher(opts *Options) (Matcher, error) {
+	if opts.Regex {
+		exps := make([]*regexp.Regexp, 0, len(opts.Contains))
+		for _, pattern := range opts.Contains {
+			exp, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --contains pattern %q: %w", pattern, err)
+			}
+			exps = append(exps, exp)
+		}
+		return func(line []byte) bool {
+			for _, exp := range exps {
+				if exp.Match(line) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	subs := make([][]byte, 0, len(opts.Contains))
+	for _, pattern := range opts.Contains {
+		subs = append(subs, []byte(pattern))
+	}
+	return func(line []byte) bool {
+		for _, sub := range subs {
+			if bytes.Contains(line, sub) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// ContainsProcess only keeps files whose content matches any of the --contains patterns.
+// Files are read concurrently, skipping directories, binary files, and files over the size limit.
+func ContainsProcess(opts *Options) Process {
+	match, err := GetMatcher(opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	jobs := max(opts.ContentJobs, 1)
+
+	return func(filenames []*Finfo) []*Finfo {
+		keep := make([]bool, len(filenames))
+		parallel(len(filenames), jobs, func(i int) {
+			fi := filenames[i]
+			if fi.IsDir || (opts.ContentMax > 0 && fi.Size > opts.ContentMax) {
+				return
+			}
+			keep[i] = SearchContent(fi, match, opts.LineNumber)
+		})
+
+		res := filenames[:0]
+		for i, fi := range filenames {
+			if keep[i] {
+				res = append(res, fi)
+			}
+		}
+		return res
+	}
+}
+
+// SearchContent reports whether any line of the file matches. If all is set, every matching
+// line number is recorded into fi.Lines, otherwise the search stops at the first match.
+func SearchContent(fi *Finfo, match Matcher, all bool) (found bool) {
+	rc, err := fi.Open()
+	if err != nil {
+		slog.Debug("error opening file for content search", "path", fi.Path, "error", err)
+		return false
+	}
+	defer rc.Close()
+
+	r := bufio.NewReaderSize(rc, probeLen)
+	head, _ := r.Peek(probeLen)
+	if bytes.IndexByte(head, 0) != -1 {
+		slog.Debug("skipping binary file", "path", fi.Path)
+		return false
+	}
+
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && match(line) {
+			found = true
+			if !all {
+				return
+			}
+			fi.Lines = append(fi.Lines, n)
+		}
+		if err != nil {
+			if err != io.EOF {
+				slog.Debug("error reading file", "path", fi.Path, "error", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package list
+
+import "testing"
+
+func TestGetMatcher(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		regex    bool
+		line     string
+		want     bool
+		err      bool
+	}{
+		{[]string{"foo"}, false, "a foo b", true, false},
+		{[]string{"foo", "bar"}, false, "bar", true, false},
+		{[]string{"foo"}, false, "fo", false, false},
+		{[]string{"(unclosed"}, false, "(unclosed", true, false},
+		{[]string{`^\d+$`}, true, "123", true, false},
+		{[]string{`^\d+$`}, true, "12a", false, false},
+		{[]string{"(unclosed"}, true, "", false, true},
+		{[]string{"ok", "[bad"}, true, "", false, true},
+	}
+	for _, tt := range tests {
+		opts := &Options{}
+		opts.Contains = tt.patterns
+		opts.Regex = tt.regex
+		match, err := GetMatcher(opts)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.patterns)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.patterns, err)
+			continue
+		}
+		if got := match([]byte(tt.line)); got != tt.want {
+			t.Errorf("%q on %q: got %v, want %v", tt.patterns, tt.line, got, tt.want)
+		}
+	}
+}
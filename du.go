@@ -0,0 +1,101 @@
+package list
+
+import (
+	"strconv"
+)
+
+// duNode accumulates the disk usage of a directory during traversal.
+type duNode struct {
+	fi     *Finfo // nil for the traversed roots
+	parent *duNode
+	size   int64
+	count  int
+}
+
+// DiskUsage tracks the directories found by TraverseFS, see --du.
+// Its methods do nothing on a nil receiver, i.e., when --du is not set.
+type DiskUsage struct {
+	nodes map[string]*duNode
+	order []*duNode // in order of discovery, parents before children
+}
+
+func NewDiskUsage(roots []string) *DiskUsage {
+	du := &DiskUsage{nodes: map[string]*duNode{}}
+	for _, root := range roots {
+		du.nodes[root] = &duNode{}
+	}
+	return du
+}
+
+// Dir registers the directory at path found in dir.
+func (du *DiskUsage) Dir(dir, path string) {
+	if du == nil {
+		return
+	}
+	n := &duNode{parent: du.nodes[dir]}
+	du.nodes[path] = n
+	du.order = append(du.order, n)
+}
+
+// Attach sets the Finfo of the directory at path, which receives the totals.
+func (du *DiskUsage) Attach(path string, fi *Finfo) {
+	if du == nil || !fi.IsDir {
+		return
+	}
+	if n, ok := du.nodes[path]; ok {
+		n.fi = fi
+	}
+}
+
+// File adds the size of the file found in dir.
+func (du *DiskUsage) File(dir string, size int64) {
+	if du == nil {
+		return
+	}
+	if n, ok := du.nodes[dir]; ok {
+		n.size += size
+		n.count++
+	}
+}
+
+// Total sums the usage of every directory into its parents, and sets the
+// Total and Count of the directories. Sorting by size uses the totals.
+func (du *DiskUsage) Total(bySize bool) {
+	if du == nil {
+		return
+	}
+	for i := len(du.order) - 1; i >= 0; i-- {
+		n := du.order[i]
+		if n.parent != nil {
+			n.parent.size += n.size
+			n.parent.count += n.count
+		}
+		if n.fi == nil {
+			continue
+		}
+		n.fi.Total = n.size
+		n.fi.Count = n.count
+		if bySize {
+			n.fi.Vany = n.size
+		}
+	}
+}
+
+var units = []string{"B", "K", "M", "G", "T", "P", "E"}
+
+// HumanSize formats bytes in binary units, e.g., 1.5M.
+func HumanSize(size int64) string {
+	if size < 1024 {
+		return strconv.FormatInt(size, 10) + units[0]
+	}
+	f := float64(size)
+	var i int
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if f < 10 {
+		return strconv.FormatFloat(f, 'f', 1, 64) + units[i]
+	}
+	return strconv.FormatFloat(f, 'f', 0, 64) + units[i]
+}
@@ -0,0 +1,50 @@
+package list
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskUsageTotals(t *testing.T) {
+	dir := t.TempDir()
+	for path, size := range map[string]int{"a/x": 10, "a/b/y": 20, "a/b/z": 5, "c/w": 1, "top": 100} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := &Options{Args: []string{dir}}
+	opts.MaxLimit = math.MaxInt64
+	opts.DiskUsage = true
+	res := Run(opts)
+
+	want := map[string]struct {
+		total int64
+		count int
+	}{"a": {35, 3}, "c": {1, 1}}
+	for _, fi := range res.Files {
+		w, ok := want[fi.Name]
+		if !ok {
+			continue
+		}
+		if fi.Total != w.total || fi.Count != w.count {
+			t.Errorf("%s: got total %d count %d, want %d %d", fi.Name, fi.Total, fi.Count, w.total, w.count)
+		}
+		if fi.Size != fi.Info.Size() {
+			t.Errorf("%s: size %d was changed from %d", fi.Name, fi.Size, fi.Info.Size())
+		}
+		if fi.DiskSize(opts) != w.total {
+			t.Errorf("%s: disk size %d, want %d", fi.Name, fi.DiskSize(opts), w.total)
+		}
+		delete(want, fi.Name)
+	}
+	if len(want) != 0 {
+		t.Errorf("directories not found: %v", want)
+	}
+}
@@ -0,0 +1,143 @@
+package list
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+	"log/slog"
+	"sort"
+)
+
+// partialLen is the amount of bytes hashed from the start of files before hashing them fully.
+const partialLen = 16 * 1024
+
+// DupesProcess only keeps files whose content is identical to another file, ordered by group.
+// Files are grouped by size, then by the hash of their first bytes, and then by the hash of their
+// whole content. With Keep set, one file of each group is dropped and the rest are kept for deletion.
+func DupesProcess(opts *Options) Process {
+	newHash, ok := Hashers[opts.DupesHash]
+	if !ok {
+		newHash = Hashers["xxhash"]
+	}
+	jobs := max(opts.HashJobs, 1)
+
+	return func(filenames []*Finfo) []*Finfo {
+		bySize := map[int64][]*Finfo{}
+		for _, fi := range filenames {
+			if fi.IsDir || fi.Size == 0 {
+				continue
+			}
+			bySize[fi.Size] = append(bySize[fi.Size], fi)
+		}
+
+		var groups [][]*Finfo
+		for _, fi := range filenames {
+			if g := bySize[fi.Size]; len(g) > 1 && g[0] == fi {
+				groups = append(groups, g)
+			}
+		}
+		slog.Debug("dupes grouped by size", "groups", len(groups))
+
+		groups = regroup(groups, jobs, func(fi *Finfo) string { return hashFile(fi, newHash, partialLen) })
+		slog.Debug("dupes grouped by partial hash", "groups", len(groups))
+		groups = regroup(groups, jobs, func(fi *Finfo) string {
+			if fi.Size <= partialLen {
+				return "partial"
+			}
+			return hashFile(fi, newHash, -1)
+		})
+		slog.Debug("dupes grouped by hash", "groups", len(groups))
+
+		var res []*Finfo
+		for i, g := range groups {
+			if opts.Keep != "" {
+				res = append(res, dropKept(g, opts.Keep)...)
+				continue
+			}
+			for _, fi := range g {
+				fi.Group = i + 1
+			}
+			res = append(res, g...)
+		}
+		return res
+	}
+}
+
+// regroup splits each group by the key of its files, computed concurrently. Groups of one are dropped.
+func regroup(groups [][]*Finfo, jobs int, key func(*Finfo) string) (res [][]*Finfo) {
+	var all []*Finfo
+	for _, g := range groups {
+		all = append(all, g...)
+	}
+
+	keys := make([]string, len(all))
+	parallel(len(all), jobs, func(i int) { keys[i] = key(all[i]) })
+
+	var n int
+	for _, g := range groups {
+		byKey := map[string][]*Finfo{}
+		var order []string
+		for _, fi := range g {
+			k := keys[n]
+			n++
+			if k == "" {
+				continue
+			}
+			if _, ok := byKey[k]; !ok {
+				order = append(order, k)
+			}
+			byKey[k] = append(byKey[k], fi)
+		}
+		for _, k := range order {
+			if len(byKey[k]) > 1 {
+				res = append(res, byKey[k])
+			}
+		}
+	}
+	return
+}
+
+// hashFile returns the hex digest of the first n bytes of the file, or of the whole file if n is negative.
+// An empty string is returned if the file could not be read.
+func hashFile(fi *Finfo, newHash func() hash.Hash, n int64) string {
+	rc, err := fi.Open()
+	if err != nil {
+		slog.Error("error opening file for hashing", "path", fi.Path, "error", err)
+		return ""
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if n >= 0 {
+		r = io.LimitReader(rc, n)
+	}
+
+	h := newHash()
+	if _, err := io.Copy(h, r); err != nil {
+		slog.Error("error hashing file", "path", fi.Path, "error", err)
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dropKept returns the group without the file kept by the policy.
+func dropKept(g []*Finfo, keep string) []*Finfo {
+	kept := make([]*Finfo, len(g))
+	copy(kept, g)
+	sort.SliceStable(kept, func(i, j int) bool {
+		a, b := kept[i], kept[j]
+		switch keep {
+		case "newest":
+			return a.ModTime.After(b.ModTime)
+		case "shortest":
+			if len(a.Path) != len(b.Path) {
+				return len(a.Path) < len(b.Path)
+			}
+			return a.Path < b.Path
+		default:
+			return a.ModTime.Before(b.ModTime)
+		}
+	})
+	slog.Debug("keeping duplicate", "path", kept[0].Path)
+	return kept[1:]
+}
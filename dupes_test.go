@@ -0,0 +1,62 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDupesProcess(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("x", partialLen)
+	contents := map[string]string{
+		"a1":     "same",
+		"sub/a2": "same",
+		"a3":     "same",
+		"b":      "diff", // same size as the a files
+		"c1":     big + "tail",
+		"c2":     big + "tail",
+		"d":      big + "TAIL", // same first bytes as the c files
+		"e1":     "",
+		"e2":     "", // empty files are not duplicates
+	}
+	writeFiles(t, dir, contents)
+
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := func() []*Finfo {
+		var res []*Finfo
+		for _, name := range []string{"a1", "sub/a2", "a3", "b", "c1", "c2", "d", "e1", "e2"} {
+			path := filepath.Join(dir, name)
+			mod := old.Add(time.Duration(len(res)) * time.Hour)
+			if err := os.Chtimes(path, mod, mod); err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, &Finfo{Name: filepath.Base(name), Path: path, Size: int64(len(contents[name])), ModTime: mod})
+		}
+		return res
+	}
+
+	tests := []struct {
+		keep string
+		want string // names with their group
+	}{
+		{"", "a1:1 a2:1 a3:1 c1:2 c2:2"},
+		{"oldest", "a2:0 a3:0 c2:0"},
+		{"newest", "a2:0 a1:0 c1:0"},
+		{"shortest", "a3:0 a2:0 c2:0"},
+	}
+	for _, tt := range tests {
+		opts := &Options{}
+		opts.Keep = tt.keep
+		var got []string
+		for _, fi := range DupesProcess(opts)(files()) {
+			got = append(got, fi.Name+":"+strconv.Itoa(fi.Group))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("keep %q: got %v, want %s", tt.keep, got, tt.want)
+		}
+	}
+}
@@ -1,23 +1,275 @@
-package list
-
-import (
-	"log/slog"
-	"os"
-	"os/exec"
-)
-
-func Exec(res *Result, opts *Options) {
-	opts.ExecArgs = append(opts.ExecArgs, res.Sar()...)
-
-	cmd := exec.Command(opts.ExecArgs[0], opts.ExecArgs[1:]...)
-	cmd.Stdout = os.Stdout
-	if opts.Debug {
-		cmd.Stderr = os.Stderr
-	}
-	cmd.Stdin = os.Stdin
-
-	err := cmd.Run()
-	if err != nil {
-		slog.Debug("error running command", "err", err)
-	}
-}
+package list
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// placeholders are replaced in exec arguments by parts of the path, like fd and parallel:
+// {} path, {/} basename, {//} parent directory, {.} path without extension, {/.} basename without extension.
+var placeholders = []string{"{}", "{/}", "{//}", "{.}", "{/.}"}
+
+// ArgMax is the space for the arguments and environment of a command, ARG_MAX of the system.
+var ArgMax = sysArgMax()
+
+// argHeadroom is left unused by batches, like xargs does, as the limit is not exact on every system.
+const argHeadroom = 2048
+
+// argSpace returns the space left for the arguments of a batched command, after the environment.
+// The environment of Windows commands is separate and not counted.
+func argSpace() int {
+	space := ArgMax - argHeadroom
+	if runtime.GOOS != "windows" {
+		for _, kv := range os.Environ() {
+			space -= argSize(kv)
+		}
+	}
+	return space
+}
+
+// argSize returns the space an argument takes: its bytes, the terminating NUL and the pointer to it.
+func argSize(arg string) int {
+	return len(arg) + 1 + strconv.IntSize/8
+}
+
+// ExpandPlaceholders replaces every placeholder in arg with the corresponding part of path.
+func ExpandPlaceholders(arg, path string) string {
+	if !strings.Contains(arg, "{") {
+		return arg
+	}
+	base := filepath.Base(path)
+	dir := filepath.ToSlash(filepath.Dir(path))
+	return strings.NewReplacer(
+		"{}", path,
+		"{/}", base,
+		"{//}", dir,
+		"{.}", strings.TrimSuffix(path, filepath.Ext(path)),
+		"{/.}", strings.TrimSuffix(base, filepath.Ext(base)),
+	).Replace(arg)
+}
+
+func hasPlaceholder(args []string) bool {
+	for _, arg := range args {
+		for _, p := range placeholders {
+			if strings.Contains(arg, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExecCommands returns the command lines Exec runs. With --exec-each or placeholders in the
+// arguments, one command is run per file. Otherwise the paths are appended to the command, or
+// put in place of a lone {}, split into batches which fit ArgMax, after the environment, and --exec-max.
+func ExecCommands(res *Result, opts *Options) (cmds [][]string) {
+	paths := make([]string, len(res.Files))
+	for i, fi := range res.Files {
+		paths[i] = FormatPath(fi, opts)
+	}
+
+	if opts.ExecEach || (!opts.ExecBatch && hasPlaceholder(opts.ExecArgs)) {
+		args := opts.ExecArgs
+		if !hasPlaceholder(args) {
+			args = append(args[:len(args):len(args)], "{}")
+		}
+		for _, path := range paths {
+			cmd := make([]string, len(args))
+			for i, arg := range args {
+				cmd[i] = ExpandPlaceholders(arg, path)
+			}
+			cmds = append(cmds, cmd)
+		}
+		return
+	}
+
+	space := argSpace()
+	base := 0
+	for _, arg := range opts.ExecArgs {
+		base += argSize(arg)
+	}
+
+	var batch []string
+	size := base
+	for _, path := range paths {
+		full := len(batch) > 0 && (size+argSize(path) > space || (opts.ExecMax > 0 && len(batch) >= opts.ExecMax))
+		if full {
+			cmds = append(cmds, batchCommand(opts.ExecArgs, batch))
+			batch, size = nil, base
+		}
+		batch = append(batch, path)
+		size += argSize(path)
+	}
+	if len(batch) > 0 || len(cmds) == 0 {
+		cmds = append(cmds, batchCommand(opts.ExecArgs, batch))
+	}
+	return
+}
+
+// batchCommand puts the paths in place of a lone {} argument, or after the arguments if there is none.
+func batchCommand(args, paths []string) []string {
+	cmd := make([]string, 0, len(args)+len(paths))
+	var placed bool
+	for _, arg := range args {
+		if arg == "{}" {
+			cmd = append(cmd, paths...)
+			placed = true
+			continue
+		}
+		cmd = append(cmd, arg)
+	}
+	if !placed {
+		cmd = append(cmd, paths...)
+	}
+	return cmd
+}
+
+// Exec runs the commands of ExecCommands, in parallel with --exec-jobs, and writes a summary of failed commands.
+// It returns the exit status to exit with: the status of the first failed command, or 127 if it could not be started.
+// With --dry-run the command lines are written instead of run.
+func Exec(res *Result, opts *Options) int {
+	cmds := ExecCommands(res, opts)
+
+	if opts.DryRun {
+		for _, args := range cmds {
+			fmt.Println(QuoteCommand(args))
+		}
+		return 0
+	}
+
+	var failed []execFailure
+	if opts.ExecJobs > 1 {
+		failed = execParallel(cmds, opts)
+	} else {
+		for _, args := range cmds {
+			cmd := exec.Command(args[0], args[1:]...)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			cmd.Stdin = os.Stdin
+
+			err := cmd.Run()
+			if err != nil {
+				slog.Debug("error running command", "err", err)
+				failed = append(failed, execFailure{args, err})
+				if opts.Halt {
+					break
+				}
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d commands failed:\n", len(failed), len(cmds))
+	for _, f := range failed {
+		var exitErr *exec.ExitError
+		if errors.As(f.err, &exitErr) {
+			fmt.Fprintln(os.Stderr, "  "+QuoteCommand(f.args))
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  %s: %v\n", QuoteCommand(f.args), f.err)
+	}
+	return ExitStatus(failed[0].err)
+}
+
+// execFailure is a command which failed, with the error it failed with.
+type execFailure struct {
+	args []string
+	err  error
+}
+
+// ExitStatus returns the exit status of a command which failed with err, like a shell:
+// the exit code of the command, 128 plus the signal if it was killed, or 127 if it could not be started.
+func ExitStatus(err error) int {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 127
+	}
+	if code := exitErr.ExitCode(); code > 0 {
+		return code
+	}
+	if sig, ok := exitSignal(exitErr); ok {
+		return 128 + sig
+	}
+	return 1
+}
+
+// execOutput is the output of a command run in parallel, written at once when it is done.
+type execOutput struct {
+	stdout, stderr bytes.Buffer
+	err            error
+}
+
+// execParallel runs the commands with --exec-jobs workers, returning the failed ones. The output of each
+// command is grouped together, and written in the order of the commands with --keep-order.
+func execParallel(cmds [][]string, opts *Options) (failed []execFailure) {
+	outputs := make([]*execOutput, len(cmds))
+	done := make(chan int)
+	var halted atomic.Bool
+
+	go func() {
+		parallel(len(cmds), opts.ExecJobs, func(i int) {
+			if halted.Load() {
+				done <- i
+				return
+			}
+			out := &execOutput{}
+			cmd := exec.Command(cmds[i][0], cmds[i][1:]...)
+			cmd.Stdout = &out.stdout
+			cmd.Stderr = &out.stderr
+			out.err = cmd.Run()
+			if out.err != nil && opts.Halt {
+				halted.Store(true)
+			}
+			outputs[i] = out
+			done <- i
+		})
+		close(done)
+	}()
+
+	write := func(i int) {
+		out := outputs[i]
+		if out == nil {
+			return
+		}
+		os.Stdout.Write(out.stdout.Bytes())
+		os.Stderr.Write(out.stderr.Bytes())
+		if out.err != nil {
+			slog.Debug("error running command", "err", out.err)
+			failed = append(failed, execFailure{cmds[i], out.err})
+		}
+	}
+
+	finished := make([]bool, len(cmds))
+	var next int
+	for i := range done {
+		if !opts.KeepOrder {
+			write(i)
+			continue
+		}
+		finished[i] = true
+		for ; next < len(cmds) && finished[next]; next++ {
+			write(next)
+		}
+	}
+	return
+}
+
+// QuoteCommand joins the command line, quoting arguments for POSIX shells where needed.
+func QuoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,15 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+// +build darwin freebsd netbsd openbsd dragonfly
+
+package list
+
+import "golang.org/x/sys/unix"
+
+// sysArgMax returns the space for the arguments and environment of a command, kern.argmax.
+func sysArgMax() int {
+	n, err := unix.SysctlUint32("kern.argmax")
+	if err != nil || n == 0 {
+		return 256 * 1024
+	}
+	return int(n)
+}
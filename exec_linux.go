@@ -0,0 +1,17 @@
+//go:build linux
+// +build linux
+
+package list
+
+import "golang.org/x/sys/unix"
+
+// sysArgMax returns the space for the arguments and environment of a command. Like sysconf(_SC_ARG_MAX)
+// it is a quarter of the stack limit, which the kernel caps at 6M, and is never below 128K.
+func sysArgMax() int {
+	limit := uint64(6 << 20)
+	var rlim unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_STACK, &rlim); err == nil && rlim.Cur != unix.RLIM_INFINITY {
+		limit = min(limit, rlim.Cur/4)
+	}
+	return int(max(limit, 128<<10))
+}
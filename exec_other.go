@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package list
+
+import "runtime"
+
+// sysArgMax returns the space for the arguments of a command, limited to 32K characters on Windows.
+func sysArgMax() int {
+	if runtime.GOOS == "windows" {
+		return 32 * 1024
+	}
+	return 128 * 1024
+}
@@ -0,0 +1,173 @@
+package list
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExecCommandsBatches(t *testing.T) {
+	prev := ArgMax
+	defer func() { ArgMax = prev }()
+
+	var files []*Finfo
+	for i := 0; i < 200; i++ {
+		files = append(files, &Finfo{Path: strings.Repeat("x", 50)})
+	}
+	res := &Result{Files: files}
+
+	opts := &Options{}
+	opts.ExecArgs = []string{"echo", "{}", "end"}
+	opts.ExecBatch = true
+
+	ArgMax += 4096 - argSpace() // 4K for arguments, whatever the environment
+	cmds := ExecCommands(res, opts)
+	if len(cmds) < 2 {
+		t.Fatalf("got %d commands, want the files split into batches", len(cmds))
+	}
+	var n int
+	for _, cmd := range cmds {
+		if cmd[0] != "echo" || cmd[len(cmd)-1] != "end" {
+			t.Errorf("paths are not in place of {}: %q", cmd)
+		}
+		size := 0
+		for _, arg := range cmd {
+			size += argSize(arg)
+		}
+		if size > argSpace() {
+			t.Errorf("batch of %d bytes is over the %d bytes available", size, argSpace())
+		}
+		n += len(cmd) - 2
+	}
+	if n != len(files) {
+		t.Errorf("got %d paths in total, want %d", n, len(files))
+	}
+
+	opts.ExecMax = 7
+	ArgMax = prev
+	for _, cmd := range ExecCommands(res, opts) {
+		if len(cmd)-2 > 7 {
+			t.Errorf("batch of %d paths is over --exec-max", len(cmd)-2)
+		}
+	}
+}
+
+func TestExecCommandsEach(t *testing.T) {
+	opts := &Options{}
+	opts.ExecArgs = []string{"mv", "{}", "{//}/new-{/.}.bak"}
+	got := ExecCommands(&Result{Files: []*Finfo{{Path: "dir/a.txt"}, {Path: "b"}}}, opts)
+	want := [][]string{{"mv", "dir/a.txt", "dir/new-a.bak"}, {"mv", "b", "./new-b.bak"}}
+	if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExpandPlaceholders(t *testing.T) {
+	tests := []struct{ arg, path, want string }{
+		{"{}", "dir/a.tar.gz", "dir/a.tar.gz"},
+		{"{/}", "dir/a.tar.gz", "a.tar.gz"},
+		{"{//}", "dir/sub/a.txt", "dir/sub"},
+		{"{//}", "a.txt", "."},
+		{"{.}", "dir/a.tar.gz", "dir/a.tar"},
+		{"{/.}", "dir.d/a", "a"},
+		{"{.}", "dir.d/a", "dir.d/a"},
+		{"--out={//}/{/.}.png", "img/x.jpg", "--out=img/x.png"},
+		{"{}{}", "a", "aa"},
+		{"plain {x}", "a", "plain {x}"},
+	}
+	for _, tt := range tests {
+		if got := ExpandPlaceholders(tt.arg, tt.path); got != tt.want {
+			t.Errorf("ExpandPlaceholders(%q, %q) = %q, want %q", tt.arg, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"sh", "-c", "exit 3"}, 3},
+		{[]string{"sh", "-c", "kill -TERM $$"}, 128 + 15},
+		{[]string{"list-no-such-command"}, 127},
+	}
+	for _, tt := range tests {
+		err := exec.Command(tt.args[0], tt.args[1:]...).Run()
+		if got := ExitStatus(err); got != tt.want {
+			t.Errorf("%q: got %d, want %d", tt.args, got, tt.want)
+		}
+	}
+	if got := ExitStatus(errors.New("other")); got != 127 {
+		t.Errorf("got %d for an error which is not an exit, want 127", got)
+	}
+}
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
+func TestExecParallel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	// later commands finish first, and each writes its output in two parts
+	var cmds [][]string
+	for i, delay := range []string{"0.3", "0.2", "0.1", "0"} {
+		cmds = append(cmds, []string{"sh", "-c", "sleep " + delay + "; printf a; sleep 0.05; echo " + string(rune('0'+i))})
+	}
+
+	tests := []struct {
+		name      string
+		keepOrder bool
+		halt      bool
+		jobs      int
+		cmds      [][]string
+		want      string
+		failed    int
+	}{
+		{"grouped", false, false, 4, cmds, "a0\na1\na2\na3\n", 0},
+		{"keep order", true, false, 4, cmds, "a0\na1\na2\na3\n", 0},
+		{"failures", true, false, 2, [][]string{{"sh", "-c", "echo x; exit 1"}, {"sh", "-c", "echo y"}}, "x\ny\n", 1},
+		{"halt", true, true, 1, [][]string{{"sh", "-c", "exit 1"}, {"sh", "-c", "echo y"}}, "", 1},
+	}
+	for _, tt := range tests {
+		opts := &Options{}
+		opts.KeepOrder, opts.Halt, opts.ExecJobs = tt.keepOrder, tt.halt, tt.jobs
+		var failed []execFailure
+		got := captureStdout(t, func() { failed = execParallel(tt.cmds, opts) })
+		if !tt.keepOrder {
+			// commands finish in any order, but the output of each is not interleaved with the others
+			lines := strings.SplitAfter(got, "\n")
+			slices.Sort(lines)
+			got = strings.Join(lines, "")
+		}
+		if got != tt.want || len(failed) != tt.failed {
+			t.Errorf("%s: got %q with %d failed, want %q with %d", tt.name, got, len(failed), tt.want, tt.failed)
+		}
+	}
+}
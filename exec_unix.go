@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package list
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// exitSignal returns the signal which killed the command, if any.
+func exitSignal(err *exec.ExitError) (int, bool) {
+	st, ok := err.Sys().(syscall.WaitStatus)
+	if !ok || !st.Signaled() {
+		return 0, false
+	}
+	return int(st.Signal()), true
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package list
+
+import (
+	"os/exec"
+)
+
+// Windows commands are not killed by signals, they always have an exit code.
+func exitSignal(_ *exec.ExitError) (int, bool) { return 0, false }
@@ -0,0 +1,134 @@
+package list
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+// exifHead is how much of a file is read for its EXIF data, which is near the start of JPEG and TIFF files.
+const exifHead = 128 * 1024
+
+// EXIF tags of the dates, see the EXIF 2.3 specification.
+const (
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+)
+
+// ExifTime returns the time a photo was taken from the EXIF data of a JPEG or TIFF file,
+// falling back to the DateTime tag if it has no original date.
+func ExifTime(fi *Finfo) (time.Time, bool) {
+	r, err := fi.Open()
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer r.Close()
+
+	head := make([]byte, exifHead)
+	n, _ := io.ReadFull(r, head)
+	tiff := exifTIFF(head[:n])
+	if tiff == nil {
+		return time.Time{}, false
+	}
+	return exifDate(tiff)
+}
+
+// exifTIFF returns the TIFF structure holding the EXIF data of a JPEG file, or the file itself if it is a TIFF.
+func exifTIFF(b []byte) []byte {
+	if bytes.HasPrefix(b, []byte("II*\x00")) || bytes.HasPrefix(b, []byte("MM\x00*")) {
+		return b
+	}
+	if !bytes.HasPrefix(b, []byte{0xFF, 0xD8}) {
+		return nil
+	}
+
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return nil
+		}
+		marker := b[i+1]
+		switch {
+		case marker == 0xFF:
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			i += 2
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// image data starts, no EXIF after it
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(b[i+2:]))
+		end := min(i+2+size, len(b))
+		if seg := b[i+4 : max(end, i+4)]; marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return seg[6:]
+		}
+		i += 2 + size
+	}
+	return nil
+}
+
+// exifDate reads DateTimeOriginal from the EXIF IFD, or DateTime from the first IFD.
+func exifDate(tiff []byte) (time.Time, bool) {
+	if len(tiff) < 8 {
+		return time.Time{}, false
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return time.Time{}, false
+	}
+
+	// entries calls fn with the tag, type, count and value or offset of every entry of the IFD at off
+	entries := func(off uint32, fn func(tag, typ uint16, count, value uint32)) {
+		if uint64(off)+2 > uint64(len(tiff)) {
+			return
+		}
+		n := int(bo.Uint16(tiff[off:]))
+		for i := 0; i < n; i++ {
+			at := int(off) + 2 + i*12
+			if at+12 > len(tiff) {
+				return
+			}
+			fn(bo.Uint16(tiff[at:]), bo.Uint16(tiff[at+2:]), bo.Uint32(tiff[at+4:]), bo.Uint32(tiff[at+8:]))
+		}
+	}
+	ascii := func(count, off uint32) string {
+		if count <= 4 || uint64(off)+uint64(count) > uint64(len(tiff)) {
+			return ""
+		}
+		return strings.TrimRight(string(tiff[off:off+count]), "\x00 ")
+	}
+
+	var modified, original string
+	var exifIFD uint32
+	entries(bo.Uint32(tiff[4:]), func(tag, typ uint16, count, value uint32) {
+		switch tag {
+		case tagDateTime:
+			modified = ascii(count, value)
+		case tagExifIFD:
+			exifIFD = value
+		}
+	})
+	if exifIFD != 0 {
+		entries(exifIFD, func(tag, typ uint16, count, value uint32) {
+			if tag == tagDateTimeOriginal {
+				original = ascii(count, value)
+			}
+		})
+	}
+
+	for _, s := range []string{original, modified} {
+		if t, err := time.ParseInLocation("2006:01:02 15:04:05", s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
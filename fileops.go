@@ -0,0 +1,199 @@
+package list
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// MovePath renames the file, falling back to copying and removing it across file systems.
+func MovePath(from, to string) error {
+	err := os.Rename(from, to)
+	if !crossDevice(err) {
+		return err
+	}
+	if err := CopyPath(from, to); err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// CopyFinfo copies the file, reading it from its archive if it is an entry of one.
+func CopyFinfo(fi *Finfo, to string) error {
+	if fi.Archive == "" {
+		return CopyPath(fi.Path, to)
+	}
+	if fi.IsDir {
+		return os.MkdirAll(to, 0o755)
+	}
+
+	r, err := fi.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := writeFile(r, to, 0o644); err != nil {
+		return err
+	}
+	return os.Chtimes(to, fi.ModTime, fi.ModTime)
+}
+
+// CopyPath copies a file, or a directory with everything beneath it, keeping modes and modification times.
+func CopyPath(from, to string) error {
+	return copyTree(from, to, copyFile)
+}
+
+// HardlinkPath hard links a file. Directories are recreated with their files hard linked, like `cp -al`.
+func HardlinkPath(from, to string) error {
+	return copyTree(from, to, func(from, to string, _ fs.FileInfo) error {
+		return os.Link(from, to)
+	})
+}
+
+// copyTree recreates the directories and symlinks beneath from, calling file for every other file.
+func copyTree(from, to string, file func(from, to string, info fs.FileInfo) error) error {
+	return filepath.Walk(from, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return fmt.Errorf("can not copy %q: not a regular file", path)
+		}
+		return file(path, target, info)
+	})
+}
+
+func copyFile(from, to string, info fs.FileInfo) error {
+	r, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := writeFile(r, to, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(to, info.ModTime(), info.ModTime())
+}
+
+func writeFile(r io.Reader, to string, perm fs.FileMode) error {
+	w, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		os.Remove(to)
+		return err
+	}
+	return w.Close()
+}
+
+// TrashDir returns the trash directory of the user, `$XDG_DATA_HOME/Trash` on Unix and `~/.Trash` on macOS.
+func TrashDir() (string, error) {
+	home, err := os.UserHomeDir()
+	switch {
+	case runtime.GOOS == "windows":
+		return "", errors.New("the trash is not supported on windows, use --delete")
+	case runtime.GOOS == "darwin":
+		return filepath.Join(home, ".Trash"), err
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "Trash"), nil
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), err
+}
+
+// TrashPath moves the file to the trash, returning its path in the trash. On Unix it follows the freedesktop.org
+// trash specification, writing a .trashinfo file with its original path so file managers can restore it.
+func TrashPath(path string) (string, error) {
+	dir, err := TrashDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "darwin" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+		to := freeName(filepath.Join(dir, filepath.Base(abs)), exists)
+		return to, MovePath(abs, to)
+	}
+
+	files, infos := filepath.Join(dir, "files"), filepath.Join(dir, "info")
+	if err := os.MkdirAll(files, 0o700); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(infos, 0o700); err != nil {
+		return "", err
+	}
+
+	// the info file is created exclusively to claim the name in the trash
+	name := filepath.Base(abs)
+	var info *os.File
+	for n := 0; ; n++ {
+		if n > 0 {
+			name = filepath.Base(freeName(filepath.Join(files, filepath.Base(abs)), func(p string) bool {
+				return exists(p) || exists(filepath.Join(infos, filepath.Base(p)+".trashinfo"))
+			}))
+		}
+		info, err = os.OpenFile(filepath.Join(infos, name+".trashinfo"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil && !exists(filepath.Join(files, name)) {
+			break
+		}
+		if err == nil {
+			info.Close()
+			os.Remove(info.Name())
+		} else if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+
+	u := url.URL{Path: abs}
+	_, err = fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n", u.EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	if cerr := info.Close(); err == nil {
+		err = cerr
+	}
+	to := filepath.Join(files, name)
+	if err == nil {
+		err = MovePath(abs, to)
+	}
+	if err != nil {
+		os.Remove(info.Name())
+		return "", err
+	}
+	return to, nil
+}
+
+// TrashInfoPath returns the path of the .trashinfo file of a file in the trash, or an empty string on macOS.
+func TrashInfoPath(trashed string) string {
+	if runtime.GOOS == "darwin" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(filepath.Dir(trashed)), "info", filepath.Base(trashed)+".trashinfo")
+}
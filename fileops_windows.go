@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package list
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// crossDevice reports whether a rename failed as the paths are on different volumes.
+func crossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
@@ -0,0 +1,107 @@
+package list
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// Record is the form of a Finfo printed by the json and ndjson formats.
+type Record struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	AbsPath   string    `json:"absPath"`
+	Kinds     []string  `json:"kinds"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	IsDir     bool      `json:"isDir"`
+	IsArchive bool      `json:"isArchive"`
+	Score     float32   `json:"score,omitempty"`
+
+	Archive string `json:"archive,omitempty"`
+	Entry   string `json:"entry,omitempty"`
+	Lines   []int  `json:"lines,omitempty"`
+	Group   int    `json:"group,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	Count   int    `json:"count,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+func NewRecord(fi *Finfo) Record {
+	abs, _ := filepath.Abs(fi.Path)
+	return Record{
+		Name:      fi.Name,
+		Path:      filepath.ToSlash(fi.Path),
+		AbsPath:   filepath.ToSlash(abs),
+		Kinds:     KindNames(fi.Mask),
+		Size:      fi.Size,
+		ModTime:   fi.ModTime,
+		IsDir:     fi.IsDir,
+		IsArchive: fi.IsArchive,
+		Score:     fi.Score,
+		Archive:   fi.Archive,
+		Entry:     fi.Entry,
+		Lines:     fi.Lines,
+		Group:     fi.Group,
+		Hash:      fi.Hash,
+		Count:     fi.Count,
+		Total:     fi.Total,
+	}
+}
+
+// KindNames returns the names of the kinds in the mask.
+func KindNames(m Mask) []string {
+	res := []string{}
+	for _, k := range Kinds {
+		if m.Has(k.bit) {
+			res = append(res, k.Name)
+		}
+	}
+	return res
+}
+
+// PrintFormat writes the files as json, ndjson, or with the template of --format or --printf.
+// A newline is written after each file with --format, but not with --printf.
+func PrintFormat(w *bufio.Writer, els []*Finfo, opts *Options) error {
+	switch opts.Format {
+	case "json":
+		w.WriteString("[\n")
+		for i, fi := range els {
+			b, err := json.Marshal(NewRecord(fi))
+			if err != nil {
+				slog.Error("error encoding file", "path", fi.Path, "error", err)
+				continue
+			}
+			w.Write(b)
+			if i < len(els)-1 {
+				w.WriteString(",")
+			}
+			w.WriteString("\n")
+		}
+		w.WriteString("]\n")
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, fi := range els {
+			if err := enc.Encode(NewRecord(fi)); err != nil {
+				slog.Error("error encoding file", "path", fi.Path, "error", err)
+			}
+		}
+	default:
+		text := opts.Printf
+		if text == "" {
+			text = opts.Format + Terminator(opts)
+		}
+		tmpl, err := ParseTemplate(text)
+		if err != nil {
+			return err
+		}
+		for _, fi := range els {
+			if err := tmpl.Execute(w, fi); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
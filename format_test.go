@@ -0,0 +1,118 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintFormatTemplates(t *testing.T) {
+	files := []*Finfo{
+		{Name: "a b.JPG", Path: "dir/a b.JPG", Size: 2048, ModTime: time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC), Mask: MaskImage},
+		{Name: "c", Path: "c", Size: 1},
+	}
+	tests := []struct {
+		format, printf string
+		want           string
+		err            string
+	}{
+		{"{{.Path}}", "", "dir/a b.JPG\nc\n", ""},
+		{"{{.Size | human}}\\t{{.Name}}", "", "2.0K\ta b.JPG\n1B\tc\n", ""},
+		{"", "{{.Name}}\\0", "a b.JPG\x00c\x00", ""},
+		{"", "{{stem .Path}}|{{ext .Path | lower}}|{{dir .Path}}\\n", "a b|.jpg|dir\nc||.\n", ""},
+		{"", "{{quote .Path}} {{date \"2006-01-02\" .ModTime}}\\n", "'dir/a b.JPG' 2024-03-09\nc 0001-01-01\n", ""},
+		{"{{kinds .Mask}}", "", "image\n\n", ""},
+		{"{{.Path", "", "", "unclosed action"},
+		{"{{nope .Path}}", "", "", `function "nope" not defined`},
+	}
+
+	for _, tt := range tests {
+		opts := &Options{}
+		opts.Format, opts.Printf = tt.format, tt.printf
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		err := PrintFormat(w, files, opts)
+		w.Flush()
+
+		name := tt.format + tt.printf
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: got error %v, want %q", name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", name, err)
+			continue
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%q: got %q, want %q", name, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":         "''",
+		"a/b-c.d":  "a/b-c.d",
+		"a b":      "'a b'",
+		"it's":     `'it'\''s'`,
+		"$HOME":    "'$HOME'",
+		"a\nb":     "'a\nb'",
+		"x=1,y@2%": "x=1,y@2%",
+	}
+	for in, want := range tests {
+		if got := ShellQuote(in); got != want {
+			t.Errorf("ShellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPrintFormatJSON(t *testing.T) {
+	files := []*Finfo{
+		{Name: "a.jpg", Path: "dir/a.jpg", Size: 3, Mask: MaskImage, Hash: "abc"},
+		{Name: "dir", Path: "dir", IsDir: true, Count: 1, Total: 3},
+	}
+	for _, format := range []string{"json", "ndjson"} {
+		opts := &Options{}
+		opts.Format = format
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		if err := PrintFormat(w, files, opts); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+
+		var records []map[string]any
+		if format == "json" {
+			if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+				t.Fatalf("json: %v in %s", err, buf.String())
+			}
+		} else {
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				var r map[string]any
+				if err := json.Unmarshal([]byte(line), &r); err != nil {
+					t.Fatalf("ndjson: %v in %q", err, line)
+				}
+				records = append(records, r)
+			}
+		}
+
+		if len(records) != 2 {
+			t.Fatalf("%s: got %d records, want 2", format, len(records))
+		}
+		file, dir := records[0], records[1]
+		if file["path"] != "dir/a.jpg" || file["hash"] != "abc" || file["kinds"].([]any)[0] != "image" {
+			t.Errorf("%s: got %v", format, file)
+		}
+		if _, ok := file["total"]; ok {
+			t.Errorf("%s: empty total is printed for a file: %v", format, file)
+		}
+		if dir["isDir"] != true || dir["count"] != 1.0 || dir["total"] != 3.0 {
+			t.Errorf("%s: got %v", format, dir)
+		}
+	}
+}
@@ -3,14 +3,16 @@ module github.com/periaate/list
 go 1.22.0
 
 require (
+	github.com/cespare/xxhash/v2 v2.3.0
 	github.com/jessevdk/go-flags v1.5.0
 	github.com/periaate/common v0.0.1
 	github.com/periaate/slice v0.0.3
+	golang.org/x/crypto v0.9.0
+	golang.org/x/sys v0.15.0
 )
 
 require golang.org/x/exp v0.0.0-20240416160154-fe59bbe5cc7f // indirect
 
 require (
 	github.com/facette/natsort v0.0.0-20181210072756-2cd4dd1e2dcb
-	golang.org/x/sys v0.15.0 // indirect
 )
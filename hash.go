@@ -0,0 +1,162 @@
+package list
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Hashers are the hashes usable with --hash and --dupes-hash.
+var Hashers = map[string]func() hash.Hash{
+	"sha256":  sha256.New,
+	"md5":     md5.New,
+	"crc32":   func() hash.Hash { return crc32.NewIEEE() },
+	"xxhash":  func() hash.Hash { return xxhash.New() },
+	"blake2b": func() hash.Hash { h, _ := blake2b.New512(nil); return h },
+}
+
+// hashByLen guesses the hash of a manifest by the length of its hex digests.
+var hashByLen = map[int]string{
+	64:  "sha256",
+	32:  "md5",
+	8:   "crc32",
+	16:  "xxhash",
+	128: "blake2b",
+}
+
+// DropDirs drops directories, used before --select when hashing so the selection is of files.
+func DropDirs(filenames []*Finfo) []*Finfo {
+	files := filenames[:0]
+	for _, fi := range filenames {
+		if !fi.IsDir {
+			files = append(files, fi)
+		}
+	}
+	return files
+}
+
+// HashProcess sets the Hash of every file, computed concurrently. Directories and files
+// which could not be read are dropped, so every printed line is a valid manifest line.
+func HashProcess(opts *Options) Process {
+	return func(filenames []*Finfo) []*Finfo {
+		files := DropDirs(filenames)
+		HashFiles(files, opts.Hash, opts.HashJobs)
+
+		res := files[:0]
+		for _, fi := range files {
+			if fi.Hash != "" {
+				res = append(res, fi)
+			}
+		}
+		return res
+	}
+}
+
+// HashFiles sets the Hash of every file which does not have one yet.
+func HashFiles(files []*Finfo, name string, jobs int) {
+	newHash, ok := Hashers[name]
+	if !ok {
+		newHash = sha256.New
+	}
+	parallel(len(files), max(jobs, 1), func(i int) {
+		if files[i].Hash == "" {
+			files[i].Hash = hashFile(files[i], newHash, -1)
+		}
+	})
+}
+
+// ReadManifest reads a sha256sum style manifest, returning the digests by path.
+func ReadManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		digest, fp, ok := strings.Cut(line, " ")
+		if !ok || len(fp) == 0 {
+			return nil, fmt.Errorf("%s:%d: invalid manifest line", path, n)
+		}
+		// the second separator is either a space or an asterisk for binary mode
+		res[filepath.ToSlash(fp[1:])] = strings.ToLower(digest)
+	}
+	return res, sc.Err()
+}
+
+// Verify compares the files against the manifest, writing every missing, changed and new file.
+// The hash is --hash, or guessed from the manifest if not given. Reports whether everything matched.
+func Verify(res *Result, opts *Options, w io.Writer) (bool, error) {
+	manifest, err := ReadManifest(opts.Verify)
+	if err != nil {
+		return false, err
+	}
+
+	name := opts.Hash
+	if name == "" {
+		for _, digest := range manifest {
+			name = hashByLen[len(digest)]
+			break
+		}
+	}
+	if _, ok := Hashers[name]; !ok {
+		return false, fmt.Errorf("could not determine the hash of %s, use --hash", opts.Verify)
+	}
+
+	var files []*Finfo
+	for _, fi := range res.Files {
+		if !fi.IsDir {
+			files = append(files, fi)
+		}
+	}
+	HashFiles(files, name, opts.HashJobs)
+
+	ok := true
+	seen := map[string]bool{}
+	for _, fi := range files {
+		fp := FormatPath(fi, opts)
+		seen[fp] = true
+		digest, found := manifest[fp]
+		switch {
+		case fi.Hash == "":
+			fmt.Fprintf(w, "%s: UNREADABLE\n", fp)
+			ok = false
+		case !found:
+			fmt.Fprintf(w, "%s: NEW\n", fp)
+			ok = false
+		case digest != fi.Hash:
+			fmt.Fprintf(w, "%s: CHANGED\n", fp)
+			ok = false
+		}
+	}
+
+	var missing []string
+	for fp := range manifest {
+		if !seen[fp] {
+			missing = append(missing, fp)
+		}
+	}
+	sort.Strings(missing)
+	for _, fp := range missing {
+		fmt.Fprintf(w, "%s: MISSING\n", fp)
+		ok = false
+	}
+	return ok, nil
+}
@@ -0,0 +1,88 @@
+package list
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashProcess(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	if err := os.WriteFile(a, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{}
+	opts.Hash = "xxhash"
+	opts.HashJobs = 2
+	files := []*Finfo{
+		{Path: a},
+		{Path: dir, IsDir: true},
+		{Path: filepath.Join(dir, "missing")},
+	}
+	res := HashProcess(opts)(files)
+	if len(res) != 1 || res[0].Path != a {
+		t.Fatalf("got %d files, want only %s", len(res), a)
+	}
+	if len(res[0].Hash) != 16 {
+		t.Errorf("xxhash digest %q is not 16 characters", res[0].Hash)
+	}
+}
+
+func TestCollectProcessHashesAfterSelect(t *testing.T) {
+	dir := t.TempDir()
+	var files []*Finfo
+	for _, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, &Finfo{Name: name, Path: path})
+	}
+	files = append([]*Finfo{{Name: "d", Path: dir, IsDir: true}}, files...)
+
+	opts := &Options{}
+	opts.Hash = "sha256"
+	opts.Select = []string{"[0:2]"}
+	res := &Result{Files: files}
+	ProcessList(res, CollectProcess(opts))
+	if len(res.Files) != 2 || res.Files[0].Name != "a" || res.Files[1].Name != "b" {
+		t.Fatalf("got %v, want a and b", res.Sar())
+	}
+	if files[3].Hash != "" {
+		t.Errorf("file outside the selection was hashed")
+	}
+}
+
+func TestVerifyGuessesXxhash(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	if err := os.WriteFile(a, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fi := &Finfo{Path: a}
+	HashFiles([]*Finfo{fi}, "xxhash", 1)
+
+	manifest := filepath.Join(dir, "manifest")
+	if err := os.WriteFile(manifest, []byte(fi.Hash+"  "+filepath.ToSlash(a)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &Options{}
+	opts.Verify = manifest
+	opts.HashJobs = 1
+	var out bytes.Buffer
+	ok, err := Verify(&Result{Files: []*Finfo{{Path: a}}}, opts, &out)
+	if err != nil || !ok {
+		t.Fatalf("got %v %v: %s", ok, err, out.String())
+	}
+
+	os.Remove(a)
+	out.Reset()
+	ok, _ = Verify(&Result{Files: []*Finfo{{Path: a}}}, opts, &out)
+	if ok || !strings.Contains(out.String(), "UNREADABLE") {
+		t.Errorf("got %v: %s, want an unreadable file", ok, out.String())
+	}
+}
@@ -0,0 +1,478 @@
+package list
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Index is the metadata of every directory beneath a root, stored on disk so traversal
+// can be answered without reading the directories. See --index and --use-index.
+type Index struct {
+	Root  string               // absolute path of the indexed directory
+	Built time.Time            // when the index was last built or refreshed
+	Dirs  map[string]*IndexDir // by slash separated path relative to Root, "." for Root
+
+	dirty bool
+}
+
+// IndexDir is a directory as it was when it was read. Its entries are reused by refreshes
+// as long as the modification time of the directory is the same.
+type IndexDir struct {
+	ModTime time.Time
+	Entries []IndexEntry // sorted by name, like os.ReadDir
+}
+
+// IndexEntry is the stat data of a file in an indexed directory.
+type IndexEntry struct {
+	Name    string
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+// indexInfo is the fs.FileInfo of an indexed file.
+type indexInfo struct{ e IndexEntry }
+
+func (i indexInfo) Name() string       { return i.e.Name }
+func (i indexInfo) Size() int64        { return i.e.Size }
+func (i indexInfo) Mode() fs.FileMode  { return i.e.Mode }
+func (i indexInfo) ModTime() time.Time { return i.e.ModTime }
+func (i indexInfo) IsDir() bool        { return i.e.Mode.IsDir() }
+func (i indexInfo) Sys() any           { return nil }
+
+// IndexDirPath returns the directory of the indexes, `$XDG_CACHE_HOME/list/index` on Unix.
+func IndexDirPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	return filepath.Join(dir, "list", "index"), err
+}
+
+// indexFile returns the file of the index of the absolute root.
+func indexFile(root string) (string, error) {
+	dir, err := IndexDirPath()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".gob"), nil
+}
+
+// BuildIndex reads every directory beneath root which traversal descends into, skipping hidden and
+// --ignore'd directories. Directories of prev whose modification time has not changed are not read again,
+// making it an incremental refresh. Changes to files which do not change their directory, e.g., writing
+// to them, are only noticed when prev is nil.
+func BuildIndex(root string, prev *Index, opts *Options) (*Index, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	idx := &Index{Root: abs, Built: time.Now(), Dirs: map[string]*IndexDir{}, dirty: true}
+
+	var reused, read int
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		path := filepath.Join(abs, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		dir, ok := prev.dir(rel)
+		if ok && dir.ModTime.Equal(info.ModTime()) {
+			reused++
+		} else {
+			read++
+			if dir, err = readIndexDir(path, info.ModTime()); err != nil {
+				return err
+			}
+		}
+		idx.Dirs[rel] = dir
+
+		for _, e := range dir.Entries {
+			if !e.Mode.IsDir() || skipIndexed(pathJoin(rel, e.Name), e.Name, opts) {
+				continue
+			}
+			if err := walk(pathJoin(rel, e.Name)); err != nil {
+				slog.Error("error indexing directory", "dir", filepath.Join(path, e.Name), "error", err)
+			}
+		}
+		return nil
+	}
+
+	if err := walk("."); err != nil {
+		return nil, err
+	}
+	slog.Debug("indexed", "root", abs, "read", read, "reused", reused)
+	return idx, nil
+}
+
+// skipIndexed reports whether the directory is left out of the index, as it is hidden or ignored.
+// Traversal reads the directories which are not indexed from disk.
+func skipIndexed(rel, name string, opts *Options) bool {
+	if !opts.NoHide && (Hide[name] || name[0] == '.') {
+		return true
+	}
+	for _, ign := range opts.Ignore {
+		if strings.Contains(filepath.FromSlash(rel), ign) {
+			return true
+		}
+	}
+	return false
+}
+
+func readIndexDir(path string, modTime time.Time) (*IndexDir, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := &IndexDir{ModTime: modTime, Entries: make([]IndexEntry, 0, len(entries))}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			slog.Error("error reading file info", "file", entry.Name(), "error", err)
+			continue
+		}
+		dir.Entries = append(dir.Entries, IndexEntry{
+			Name:    info.Name(),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+		})
+	}
+	return dir, nil
+}
+
+func pathJoin(rel, name string) string {
+	if rel == "." {
+		return name
+	}
+	return rel + "/" + name
+}
+
+// dir returns the indexed directory, nil-safe for building without a previous index.
+func (idx *Index) dir(rel string) (*IndexDir, bool) {
+	if idx == nil {
+		return nil, false
+	}
+	dir, ok := idx.Dirs[rel]
+	return dir, ok
+}
+
+// Files returns the number of indexed files, including directories.
+func (idx *Index) Files() (n int) {
+	for _, dir := range idx.Dirs {
+		n += len(dir.Entries)
+	}
+	return
+}
+
+// Save writes the index, replacing the previous one of its root.
+func (idx *Index) Save() error {
+	name, err := indexFile(idx.Root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".index-*")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(tmp).Encode(idx)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	idx.dirty = false
+	return nil
+}
+
+// LoadIndex reads the index of the root, or returns nil if it has none.
+func LoadIndex(root string) (*Index, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	name, err := indexFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	idx := &Index{}
+	if err := gob.NewDecoder(f).Decode(idx); err != nil {
+		return nil, fmt.Errorf("reading index of %s: %w", abs, err)
+	}
+	return idx, nil
+}
+
+// Indexes returns every saved index.
+func Indexes() ([]*Index, error) {
+	dir, err := IndexDirPath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*Index
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".gob" {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		idx := &Index{}
+		err = gob.NewDecoder(f).Decode(idx)
+		f.Close()
+		if err != nil {
+			slog.Error("invalid index", "file", entry.Name(), "error", err)
+			continue
+		}
+		res = append(res, idx)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Root < res[j].Root })
+	return res, nil
+}
+
+// RunIndex runs the --index command on the directories given as arguments, or the working directory:
+// build reads them from scratch, update refreshes their indexes, list prints the indexes and drop removes them.
+func RunIndex(opts *Options) int {
+	roots := opts.Args
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	if opts.Index == "list" {
+		indexes, err := Indexes()
+		if err != nil {
+			slog.Error("error reading indexes", "error", err)
+			return 1
+		}
+		for _, idx := range indexes {
+			fmt.Printf("%s\t%s\t%d dirs\t%d files\n", idx.Root, idx.Built.Format(time.DateTime), len(idx.Dirs), idx.Files())
+		}
+		return 0
+	}
+
+	var failed int
+	for _, root := range roots {
+		if err := runIndex(opts.Index, root, opts); err != nil {
+			slog.Error("error indexing", "root", root, "error", err)
+			failed++
+		}
+	}
+	return min(failed, 1)
+}
+
+func runIndex(cmd, root string, opts *Options) error {
+	if cmd == "drop" {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		name, err := indexFile(abs)
+		if err != nil {
+			return err
+		}
+		return os.Remove(name)
+	}
+
+	var prev *Index
+	if cmd == "update" {
+		var err error
+		if prev, err = LoadIndex(root); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	idx, err := BuildIndex(root, prev, opts)
+	if err != nil {
+		return err
+	}
+	if err := idx.Save(); err != nil {
+		return err
+	}
+	if !opts.Quiet {
+		fmt.Fprintf(os.Stderr, "indexed %d dirs and %d files of %s in %s\n", len(idx.Dirs), idx.Files(), idx.Root, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// Indexed answers reading directories from the indexes of the traversed roots, see --use-index.
+// Its methods read from disk on a nil receiver, or for directories which are not indexed.
+type Indexed struct {
+	opts    *Options
+	indexes []*Index
+}
+
+// OpenIndexed loads the indexes covering the roots, refreshing the ones older than --index-max-age.
+func OpenIndexed(roots []string, opts *Options) *Indexed {
+	ix := &Indexed{opts: opts}
+	saved, err := savedIndexes()
+	if err != nil {
+		slog.Error("error reading indexes", "error", err)
+		return ix
+	}
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil || ix.find(abs) != nil {
+			continue
+		}
+		idx, err := loadCovering(abs, saved)
+		if err != nil {
+			slog.Error("error loading index", "root", root, "error", err)
+			continue
+		}
+		if idx == nil {
+			slog.Debug("no index covers root, reading from disk", "root", root)
+			continue
+		}
+
+		if opts.IndexMaxAge > 0 && time.Since(idx.Built) > opts.IndexMaxAge {
+			slog.Debug("refreshing stale index", "root", idx.Root, "built", idx.Built)
+			if fresh, err := BuildIndex(idx.Root, idx, opts); err != nil {
+				slog.Error("error refreshing index", "root", idx.Root, "error", err)
+			} else {
+				idx = fresh
+			}
+		}
+		ix.indexes = append(ix.indexes, idx)
+	}
+	return ix
+}
+
+// savedIndexes returns the names of the index files, so the directories without one are found without opening files.
+func savedIndexes() (map[string]bool, error) {
+	dir, err := IndexDirPath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	saved := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		saved[entry.Name()] = true
+	}
+	return saved, nil
+}
+
+// loadCovering loads the index of the directory, or of the closest directory above it which has one.
+func loadCovering(abs string, saved map[string]bool) (*Index, error) {
+	for dir := abs; ; {
+		name, err := indexFile(dir)
+		if err != nil {
+			return nil, err
+		}
+		if saved[filepath.Base(name)] {
+			return LoadIndex(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func (ix *Indexed) find(abs string) *Index {
+	for _, idx := range ix.indexes {
+		if _, ok := beneath(abs, idx.Root); ok || idx.Root == filepath.Dir(idx.Root) {
+			return idx
+		}
+	}
+	return nil
+}
+
+// ReadDir returns the files of the directory from its index. With --index-verify the modification
+// time of the directory is checked first, and a changed directory is read from disk and updated in the index.
+func (ix *Indexed) ReadDir(path string, depth int, opts *Options) []fs.FileInfo {
+	if ix == nil {
+		return TraverseDir(path, depth, opts)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return TraverseDir(path, depth, opts)
+	}
+	idx := ix.find(abs)
+	if idx == nil {
+		return TraverseDir(path, depth, opts)
+	}
+	rel, err := filepath.Rel(idx.Root, abs)
+	if err != nil {
+		return TraverseDir(path, depth, opts)
+	}
+	rel = filepath.ToSlash(rel)
+
+	dir, ok := idx.Dirs[rel]
+	if opts.IndexVerify || !ok {
+		info, err := os.Stat(abs)
+		switch {
+		case err != nil:
+			slog.Debug("indexed directory is gone", "dir", path, "error", err)
+			return nil
+		case !ok || !dir.ModTime.Equal(info.ModTime()):
+			slog.Debug("reading changed directory", "dir", path)
+			if dir, err = readIndexDir(abs, info.ModTime()); err != nil {
+				return TraverseDir(path, depth, opts)
+			}
+			idx.Dirs[rel] = dir
+			idx.dirty = true
+		}
+	}
+
+	files := make([]fs.FileInfo, len(dir.Entries))
+	for i, e := range dir.Entries {
+		files[i] = indexInfo{e}
+	}
+	return files
+}
+
+// Close saves the indexes which were refreshed or updated while traversing.
+func (ix *Indexed) Close() {
+	if ix == nil {
+		return
+	}
+	for _, idx := range ix.indexes {
+		if !idx.dirty {
+			continue
+		}
+		if err := idx.Save(); err != nil {
+			slog.Error("error saving index", "root", idx.Root, "error", err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package list
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBuildIndexSkips(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/x":            "",
+		".git/objects/y": "",
+		"build/m/z":      "",
+		"b/build/w":      "",
+	})
+
+	tests := []struct {
+		name   string
+		noHide bool
+		ignore []string
+		want   []string
+	}{
+		{"hidden", false, nil, []string{".", "a", "b", "b/build", "build", "build/m"}},
+		{"ignored", false, []string{"build"}, []string{".", "a", "b"}},
+		{"not hidden", true, []string{"build"}, []string{".", ".git", ".git/objects", "a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{}
+			opts.NoHide = tt.noHide
+			opts.Ignore = tt.ignore
+			idx, err := BuildIndex(dir, nil, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for rel := range idx.Dirs {
+				got = append(got, rel)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("indexed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseIndex(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a/x": "", "a/b/y": "", ".hidden/z": ""})
+
+	opts := &Options{}
+	idx, err := BuildIndex(dir, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+	// files added since are only found by reading from disk
+	writeFiles(t, dir, map[string]string{"a/b/new": "", ".hidden/new": ""})
+
+	// the index of a directory above the traversed one is used
+	opts = &Options{Args: []string{filepath.Join(dir, "a")}}
+	opts.MaxLimit = math.MaxInt64
+	opts.ToDepth = math.MaxInt64
+	opts.UseIndex = true
+	var got []string
+	for _, fi := range Run(opts).Files {
+		got = append(got, fi.Name)
+	}
+	slices.Sort(got)
+	if want := []string{"b", "x", "y"}; !slices.Equal(got, want) {
+		t.Errorf("got %v from the index, want %v", got, want)
+	}
+
+	// hidden directories are not indexed, so they are read from disk
+	opts = &Options{Args: []string{filepath.Join(dir, ".hidden")}}
+	opts.MaxLimit = math.MaxInt64
+	opts.UseIndex = true
+	got = nil
+	for _, fi := range Run(opts).Files {
+		got = append(got, fi.Name)
+	}
+	slices.Sort(got)
+	if want := []string{"new", "z"}; !slices.Equal(got, want) {
+		t.Errorf("got %v from disk, want %v", got, want)
+	}
+}
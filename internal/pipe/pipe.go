@@ -0,0 +1,40 @@
+// Package pipe reads the elements piped to list and slice.
+package pipe
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+)
+
+// Read reads the elements piped to stdin, separated by NUL if null is set and by newlines otherwise.
+func Read(null bool) (res []string) {
+	fileInfo, _ := os.Stdin.Stat()
+	if (fileInfo.Mode() & os.ModeCharDevice) != 0 {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(nil, 1<<20)
+	if null {
+		scanner.Split(ScanNull)
+	}
+	for scanner.Scan() {
+		res = append(res, scanner.Text())
+	}
+	return
+}
+
+// ScanNull is a bufio.SplitFunc for NUL separated elements.
+func ScanNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
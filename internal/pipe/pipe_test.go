@@ -0,0 +1,32 @@
+package pipe
+
+import (
+	"bufio"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScanNull(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a\x00b\x00", []string{"a", "b"}},
+		{"a\x00b", []string{"a", "b"}},
+		{"with space\x00new\nline\x00", []string{"with space", "new\nline"}},
+		{"\x00a", []string{"", "a"}},
+	}
+	for _, tt := range tests {
+		sc := bufio.NewScanner(strings.NewReader(tt.in))
+		sc.Split(ScanNull)
+		var got []string
+		for sc.Scan() {
+			got = append(got, sc.Text())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
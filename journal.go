@@ -0,0 +1,410 @@
+package list
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Journal records the file operations of a single run of list, so they can be undone with --undo.
+type Journal struct {
+	ID      string      `json:"id"`
+	Time    time.Time   `json:"time"`
+	Command []string    `json:"command"`
+	Action  string      `json:"action"`
+	Ops     []JournalOp `json:"ops"`
+	Undone  *time.Time  `json:"undone,omitempty"`
+}
+
+// JournalOp is an operation from an absolute path to another, with the state of the destination right after
+// it was done. Undo refuses to revert operations whose destination has changed since.
+type JournalOp struct {
+	Action  string      `json:"action"`
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Mode    fs.FileMode `json:"mode"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"modTime"`
+	// Contents are the files beneath a copied or hard linked directory, which undo removes.
+	Contents []JournalFile `json:"contents,omitempty"`
+	// Overwritten is where the file overwritten at To was moved, restored by undo.
+	Overwritten string `json:"overwritten,omitempty"`
+}
+
+// JournalFile is the state of a file beneath a directory, relative to it.
+type JournalFile struct {
+	Path    string      `json:"path"`
+	Mode    fs.FileMode `json:"mode"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"modTime"`
+}
+
+// JournalDir returns the directory of the journals, `$XDG_STATE_HOME/list/journal` on Unix.
+func JournalDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "list", "journal"), nil
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		dir, err := os.UserConfigDir()
+		return filepath.Join(dir, "list", "journal"), err
+	}
+	home, err := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "list", "journal"), err
+}
+
+// RecordJournal writes a journal of the done operations which can be undone. Deletes can not be and are not recorded.
+func RecordJournal(done []Op) {
+	j := Journal{Time: time.Now(), Command: os.Args}
+	for _, op := range done {
+		if op.To == "" || op.after == nil {
+			continue
+		}
+		from, err := filepath.Abs(op.From)
+		if err != nil {
+			continue
+		}
+		to, err := filepath.Abs(op.To)
+		if err != nil {
+			continue
+		}
+		jop := JournalOp{
+			Action:      op.Action,
+			From:        from,
+			To:          to,
+			Mode:        op.after.Mode(),
+			Size:        op.after.Size(),
+			ModTime:     op.after.ModTime(),
+			Overwritten: op.aside,
+		}
+		if !jop.movesBack() && op.after.IsDir() {
+			// an unreadable directory is recorded without its contents, so it is never removed by undo
+			jop.Contents, _ = dirContents(to)
+		}
+		j.Action = op.Action
+		j.Ops = append(j.Ops, jop)
+	}
+	if len(j.Ops) == 0 {
+		return
+	}
+
+	if err := writeNewJournal(&j); err != nil {
+		slog.Error("error writing journal", "error", err)
+	}
+}
+
+// dirContents returns the state of every file beneath the directory, in lexical order.
+func dirContents(dir string) (res []JournalFile, err error) {
+	err = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		f := JournalFile{Path: path[len(dir)+1:], Mode: info.Mode()}
+		if !info.IsDir() {
+			f.Size, f.ModTime = info.Size(), info.ModTime()
+		}
+		res = append(res, f)
+		return nil
+	})
+	return
+}
+
+// moveAside moves a file about to be overwritten into the journal directory, returning its new path.
+func moveAside(path string) (string, error) {
+	dir, err := JournalDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "overwritten")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(dir, "")
+	if err != nil {
+		return "", err
+	}
+	to := filepath.Join(tmp, filepath.Base(path))
+	if err := MovePath(path, to); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return to, nil
+}
+
+// writeNewJournal writes the journal under an unused id made of its time.
+func writeNewJournal(j *Journal) error {
+	dir, err := JournalDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	id := j.Time.Format("20060102-150405")
+	for n := 1; ; n++ {
+		j.ID = id
+		if n > 1 {
+			j.ID = fmt.Sprintf("%s-%d", id, n)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, j.ID+".json"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "\t")
+		err = enc.Encode(j)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+// Journals returns the journals from the oldest to the newest.
+func Journals() ([]*Journal, error) {
+	dir, err := JournalDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*Journal
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		j := &Journal{}
+		if err := json.Unmarshal(b, j); err != nil {
+			slog.Error("invalid journal", "file", entry.Name(), "error", err)
+			continue
+		}
+		res = append(res, j)
+	}
+	sort.SliceStable(res, func(i, k int) bool { return res[i].Time.Before(res[k].Time) })
+	return res, nil
+}
+
+// PrintJournals writes the id, time, action, number of operations and command of every journal, the newest last.
+func PrintJournals() int {
+	journals, err := Journals()
+	if err != nil {
+		slog.Error("error reading journals", "error", err)
+		return 1
+	}
+	for _, j := range journals {
+		state := ""
+		if j.Undone != nil {
+			state = " (undone)"
+		}
+		fmt.Printf("%s\t%s\t%s %d operations%s\t%s\n", j.ID, j.Time.Format(time.DateTime), j.Action, len(j.Ops), state, QuoteCommand(j.Command))
+	}
+	return 0
+}
+
+// Undo reverts the operations of the journal with the id of --undo, or of the newest journal not yet undone.
+// The id can also be given as an argument. Nothing is reverted if any destination has changed since,
+// or if any original path is taken.
+func Undo(opts *Options) int {
+	id := opts.Undo
+	if id == "last" && len(opts.Args) > 0 {
+		id = opts.Args[0]
+	}
+
+	j, err := findJournal(id)
+	if err != nil {
+		slog.Error("error finding journal", "error", err)
+		return 1
+	}
+
+	var problems []string
+	for i := range j.Ops {
+		if err := j.verify(i); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "can not undo %s, files have changed since:\n  %s\n", j.ID, strings.Join(problems, "\n  "))
+		return 1
+	}
+
+	if opts.DryRun {
+		for i := len(j.Ops) - 1; i >= 0; i-- {
+			fmt.Println(j.Ops[i].undoString())
+		}
+		return 0
+	}
+
+	// operations are undone in reverse, so every file is where its operation left it when it is undone
+	var failed int
+	for i := len(j.Ops) - 1; i >= 0; i-- {
+		if err := j.Ops[i].undo(); err != nil {
+			slog.Error("error undoing operation", "action", j.Ops[i].Action, "path", j.Ops[i].To, "error", err)
+			failed++
+		}
+	}
+
+	now := time.Now()
+	j.Undone = &now
+	if err := rewriteJournal(j); err != nil {
+		slog.Error("error marking journal as undone", "error", err)
+	}
+	if !opts.Quiet {
+		fmt.Fprintf(os.Stderr, "undid %d operations of %s, %d failed\n", len(j.Ops)-failed, j.ID, failed)
+	}
+	return min(failed, 1)
+}
+
+func findJournal(id string) (*Journal, error) {
+	journals, err := Journals()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(journals) - 1; i >= 0; i-- {
+		j := journals[i]
+		switch {
+		case id == "last" && j.Undone == nil:
+			return j, nil
+		case j.ID == id && j.Undone != nil:
+			return nil, fmt.Errorf("%s was already undone at %s", id, j.Undone.Format(time.DateTime))
+		case j.ID == id:
+			return j, nil
+		}
+	}
+	if id == "last" {
+		return nil, errors.New("nothing to undo")
+	}
+	return nil, fmt.Errorf("no journal with id %q, see --journal", id)
+}
+
+func rewriteJournal(j *Journal) error {
+	dir, err := JournalDir()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(j, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, j.ID+".json"), append(b, '\n'), 0o600)
+}
+
+// verify checks that the destination of the i:th operation is as it was left, following it through the
+// later operations, and that its original path is free to move it back to, unless a later operation took it.
+func (j *Journal) verify(i int) error {
+	op := j.Ops[i]
+	at, taken := op.To, false
+	for _, later := range j.Ops[i+1:] {
+		if rel, ok := beneath(at, later.From); ok && later.movesBack() {
+			at = filepath.Join(later.To, rel)
+		}
+		if _, ok := beneath(op.From, later.To); ok {
+			taken = true
+		}
+	}
+
+	info, err := os.Lstat(at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", at, errors.Unwrap(err))
+	}
+	if info.Mode() != op.Mode || (!info.IsDir() && (info.Size() != op.Size || !info.ModTime().Equal(op.ModTime))) {
+		return fmt.Errorf("%s: modified", at)
+	}
+	if !op.movesBack() && info.IsDir() {
+		// removing the directory must not remove files added to it since
+		contents, err := dirContents(at)
+		if err != nil || !slices.EqualFunc(contents, op.Contents, JournalFile.equal) {
+			return fmt.Errorf("%s: contents modified", at)
+		}
+	}
+	if op.movesBack() && !taken && exists(op.From) {
+		return fmt.Errorf("%s: exists", op.From)
+	}
+	if op.Overwritten != "" && !exists(op.Overwritten) {
+		return fmt.Errorf("%s: overwritten file is missing from %s", op.To, op.Overwritten)
+	}
+	return nil
+}
+
+func (f JournalFile) equal(g JournalFile) bool {
+	return f.Path == g.Path && f.Mode == g.Mode && f.Size == g.Size && f.ModTime.Equal(g.ModTime)
+}
+
+// beneath returns the path of path relative to dir, if it is dir or beneath it.
+func beneath(path, dir string) (string, bool) {
+	if path == dir {
+		return ".", true
+	}
+	rel, ok := strings.CutPrefix(path, dir+string(filepath.Separator))
+	return rel, ok
+}
+
+// movesBack reports whether undoing moves the file back, instead of removing the file created by the operation.
+func (op JournalOp) movesBack() bool {
+	return op.Action == ActMove || op.Action == ActRename || op.Action == ActTrash
+}
+
+// undo reverts the operation, then restores the file it overwrote.
+func (op JournalOp) undo() error {
+	if err := op.revert(); err != nil || op.Overwritten == "" {
+		return err
+	}
+	if err := MovePath(op.Overwritten, op.To); err != nil {
+		return err
+	}
+	os.Remove(filepath.Dir(op.Overwritten))
+	return nil
+}
+
+func (op JournalOp) revert() error {
+	if !op.movesBack() {
+		return os.RemoveAll(op.To)
+	}
+	if exists(op.From) {
+		return fmt.Errorf("%s exists", op.From)
+	}
+	if err := os.MkdirAll(filepath.Dir(op.From), 0o755); err != nil {
+		return err
+	}
+	if err := MovePath(op.To, op.From); err != nil {
+		return err
+	}
+	if op.Action == ActTrash {
+		if info := TrashInfoPath(op.To); info != "" {
+			os.Remove(info)
+		}
+	}
+	return nil
+}
+
+func (op JournalOp) undoString() string {
+	res := QuoteCommand([]string{ActMove, op.To, op.From})
+	if !op.movesBack() {
+		res = QuoteCommand([]string{ActDelete, op.To})
+	}
+	if op.Overwritten != "" {
+		res += "\n" + QuoteCommand([]string{ActMove, op.Overwritten, op.To})
+	}
+	return res
+}
@@ -0,0 +1,84 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndoCopy(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/a.txt":     "a",
+		"src/sub/b.txt": "b",
+		"c.txt":         "new",
+		"out/c.txt":     "old",
+	})
+	opts := &Options{}
+	opts.Conflict = "overwrite"
+	opts.Quiet = true
+	opts.Undo = "last"
+
+	copyOp := func(from, to string) Op {
+		from, to = filepath.Join(dir, from), filepath.Join(dir, to)
+		return Op{Action: ActCopy, From: from, To: to, fi: &Finfo{Path: from}}
+	}
+	done := ApplyOps([]Op{copyOp("src", "out/src"), copyOp("c.txt", "out/c.txt")}, opts)
+	if len(done) != 2 {
+		t.Fatalf("%d operations done, want 2", len(done))
+	}
+	if got := readFile(t, filepath.Join(dir, "out/c.txt")); got != "new" {
+		t.Fatalf("out/c.txt has %q, want %q", got, "new")
+	}
+
+	// a file added to the copied directory must not be removed
+	added := filepath.Join(dir, "out/src/sub/added.txt")
+	writeFiles(t, dir, map[string]string{"out/src/sub/added.txt": "mine"})
+	if Undo(opts) == 0 {
+		t.Fatal("undo removed a directory with a file added to it")
+	}
+	if readFile(t, added) != "mine" {
+		t.Fatal("the added file was changed")
+	}
+
+	if err := os.Remove(added); err != nil {
+		t.Fatal(err)
+	}
+	if Undo(opts) != 0 {
+		t.Fatal("undo failed")
+	}
+	if exists(filepath.Join(dir, "out/src")) {
+		t.Error("the copied directory was not removed")
+	}
+	if got := readFile(t, filepath.Join(dir, "out/c.txt")); got != "old" {
+		t.Errorf("out/c.txt has %q after undo, want the overwritten %q", got, "old")
+	}
+	if got := readFile(t, filepath.Join(dir, "c.txt")); got != "new" {
+		t.Errorf("c.txt has %q after undo, want %q", got, "new")
+	}
+}
+
+func TestUndoMoveOverwrite(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "new", "out/a.txt": "old"})
+	opts := &Options{}
+	opts.Conflict = "overwrite"
+	opts.Quiet = true
+	opts.Undo = "last"
+
+	from, to := filepath.Join(dir, "a.txt"), filepath.Join(dir, "out/a.txt")
+	if done := ApplyOps([]Op{{Action: ActMove, From: from, To: to}}, opts); len(done) != 1 {
+		t.Fatal("the move failed")
+	}
+	if Undo(opts) != 0 {
+		t.Fatal("undo failed")
+	}
+	if got := readFile(t, from); got != "new" {
+		t.Errorf("a.txt has %q after undo, want %q", got, "new")
+	}
+	if got := readFile(t, to); got != "old" {
+		t.Errorf("out/a.txt has %q after undo, want the overwritten %q", got, "old")
+	}
+}
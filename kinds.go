@@ -0,0 +1,163 @@
+package list
+
+import (
+	"encoding/hex"
+	"log/slog"
+	"strings"
+)
+
+// Mask is a set of kinds, where bit n is set if the file is of the nth registered kind.
+// Masks are never modified in place, so they can be shared freely.
+type Mask []uint64
+
+func bitMask(n int) Mask {
+	m := make(Mask, n/64+1)
+	m[n/64] = 1 << (n % 64)
+	return m
+}
+
+// Or returns the union of the masks.
+func (m Mask) Or(o Mask) Mask {
+	switch {
+	case o.IsZero():
+		return m
+	case m.IsZero():
+		return o
+	}
+	if len(m) < len(o) {
+		m, o = o, m
+	}
+	res := make(Mask, len(m))
+	copy(res, m)
+	for i, v := range o {
+		res[i] |= v
+	}
+	return res
+}
+
+// Any reports whether the masks share any kind.
+func (m Mask) Any(o Mask) bool {
+	for i := 0; i < len(m) && i < len(o); i++ {
+		if m[i]&o[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether m contains every kind of o.
+func (m Mask) Has(o Mask) bool {
+	if o.IsZero() {
+		return false
+	}
+	for i, v := range o {
+		if v == 0 {
+			continue
+		}
+		if i >= len(m) || m[i]&v != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Mask) IsZero() bool {
+	for _, v := range m {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Kind is a named file type, matched by extension and optionally by magic bytes.
+// Includes lists the kinds a kind groups together, e.g., filtering by media includes images.
+// Is lists the kinds files of this kind also are, e.g., zip files are archives.
+type Kind struct {
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Exts     []string `json:"exts,omitempty"`
+	Includes []string `json:"includes,omitempty"`
+	Is       []string `json:"is,omitempty"`
+	Magic    []Magic  `json:"magic,omitempty"`
+	Quick    string   `json:"quick,omitempty"` // quick command letter which includes this kind
+	Color    string   `json:"color,omitempty"` // SGR sequence used when coloring output, e.g., 01;35
+
+	Mask     Mask `json:"-"` // matched against the masks of files when filtering
+	FileMask Mask `json:"-"` // given to files of this kind
+	bit      Mask
+	sigs     []Signature
+}
+
+// Magic is the configuration form of a Signature.
+type Magic struct {
+	Offset int    `json:"offset"`
+	Hex    string `json:"hex"`
+	Weak   bool   `json:"weak,omitempty"`
+}
+
+var (
+	Kinds      []*Kind
+	KindByName = map[string]*Kind{}
+	CntMap     = map[string]Mask{}
+	bits       int
+)
+
+// RegisterKind registers the kind and returns its mask. Registering a name which already
+// exists extends the existing kind with the given aliases, extensions and magic bytes.
+func RegisterKind(k *Kind) Mask {
+	if prev, ok := KindByName[k.Name]; ok {
+		prev.Aliases = append(prev.Aliases, k.Aliases...)
+		prev.Exts = append(prev.Exts, k.Exts...)
+		prev.Magic = append(prev.Magic, k.Magic...)
+		k = prev
+	} else {
+		k.bit = bitMask(bits)
+		k.Mask, k.FileMask = k.bit, k.bit
+		bits++
+		for _, name := range k.Includes {
+			k.Mask = k.Mask.Or(StrToMask(name))
+		}
+		for _, name := range k.Is {
+			if parent, ok := KindByName[name]; ok {
+				k.FileMask = k.FileMask.Or(parent.FileMask)
+			}
+		}
+		Kinds = append(Kinds, k)
+	}
+
+	KindByName[k.Name] = k
+	for _, alias := range k.Aliases {
+		KindByName[alias] = k
+	}
+	for _, ext := range k.Exts {
+		ext = strings.ToLower(ext)
+		CntMap[ext] = CntMap[ext].Or(k.FileMask)
+	}
+
+	k.sigs = k.sigs[:0]
+	for _, m := range k.Magic {
+		b, err := hex.DecodeString(m.Hex)
+		if err != nil {
+			slog.Error("invalid magic bytes", "kind", k.Name, "hex", m.Hex, "error", err)
+			continue
+		}
+		k.sigs = append(k.sigs, Signature{m.Offset, b, k.FileMask, m.Weak, ""})
+	}
+	return k.Mask
+}
+
+// RegisterKinds registers each kind along with its quick command letter.
+func RegisterKinds(kinds []*Kind) {
+	for _, k := range kinds {
+		if k.Name == "" {
+			slog.Error("kind without a name", "exts", k.Exts)
+			continue
+		}
+		RegisterKind(k)
+
+		if k.Quick != "" {
+			RegisterQuick(k.Quick, "-i", k.Name)
+		}
+	}
+}
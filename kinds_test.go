@@ -0,0 +1,62 @@
+package list
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestMaskOps(t *testing.T) {
+	a, b, far := bitMask(1), bitMask(2), bitMask(130)
+	ab := a.Or(b)
+	tests := []struct {
+		name      string
+		got, want bool
+	}{
+		{"a has a", a.Has(a), true},
+		{"ab has a", ab.Has(a), true},
+		{"a has ab", a.Has(ab), false},
+		{"ab any b", ab.Any(b), true},
+		{"a any b", a.Any(b), false},
+		{"far or a has far", far.Or(a).Has(far), true},
+		{"a or far has a", a.Or(far).Has(a), true},
+		{"a any far", a.Any(far), false},
+		{"far has nothing", far.Has(nil), false},
+		{"zero or zero", Mask(nil).Or(Mask{0, 0}).IsZero(), true},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if a.Has(b) || len(a) != 1 {
+		t.Errorf("a was modified by Or: %v", a)
+	}
+}
+
+func TestRegisterKindsBeyond64(t *testing.T) {
+	// the registry is global, restore it for the other tests
+	prevKinds, prevByName, prevCnt, prevBits := slices.Clone(Kinds), maps.Clone(KindByName), maps.Clone(CntMap), bits
+	t.Cleanup(func() { Kinds, KindByName, CntMap, bits = prevKinds, prevByName, prevCnt, prevBits })
+
+	var kinds []*Kind
+	for i := 0; i < 70; i++ {
+		kinds = append(kinds, &Kind{Name: fmt.Sprintf("test-kind-%d", i), Exts: []string{fmt.Sprintf(".tk%d", i)}})
+	}
+	kinds = append(kinds, &Kind{Name: "test-group", Aliases: []string{"tg"}, Includes: []string{"test-kind-0", "test-kind-69"}})
+	kinds = append(kinds, &Kind{Name: "test-zip", Exts: []string{".TZIP"}, Is: []string{Archive}})
+	RegisterKinds(kinds)
+
+	first, last := ExtMask("a.tk0"), ExtMask("a.TK69")
+	if first.Any(last) {
+		t.Errorf("the first and last kinds share a bit: %v %v", first, last)
+	}
+	group := StrToMask("tg")
+	if !group.Any(first) || !group.Any(last) || group.Any(ExtMask("a.tk1")) {
+		t.Errorf("test-group includes the wrong kinds: %v", group)
+	}
+	if m := ExtMask("a.tzip"); !m.Has(MaskArchive) || !m.Has(StrToMask("test-zip")) {
+		t.Errorf("test-zip files are not archives: %v", m)
+	}
+}
@@ -1,208 +1,258 @@
-package list
-
-import (
-	"log"
-	"log/slog"
-	"math"
-	"os"
-
-	gf "github.com/jessevdk/go-flags"
-	"github.com/periaate/common"
-)
-
-func Run(opts *Options) *Result {
-	res := &Result{Files: []*Finfo{}}
-	filters := InitFilters(CollectFilters(opts), res)
-	processes := CollectProcess(opts)
-	traverser := GetTraverser(opts)
-
-	traverser(opts, filters)
-	ProcessList(res, processes)
-	return res
-}
-
-func Initialize(opts *Options) (*Result, []Filter, []Process) {
-	res := &Result{Files: []*Finfo{}}
-	filters := CollectFilters(opts)
-	processes := CollectProcess(opts)
-
-	return res, filters, processes
-}
-
-type ModeOpts struct {
-	ArgMode  bool   `short:"l" long:"arg" description:"Skips listing, uses the input arguments as elements."`
-	FileMode string `long:"file" description:"Reads the files given as arguments and uses either words or lines as elements." choice:"words" choice:"w" choice:"lines" choice:"l"`
-}
-
-type ListingOpts struct {
-	Recurse   bool     `short:"r" long:"recurse" description:"Recursively list files in subdirectories. Directory traversal is done iteratively and breadth first."`
-	Archive   bool     `short:"z" description:"Treat zip archives as directories."`
-	ToDepth   int      `short:"T" long:"todepth" description:"List files to a certain depth." default:"0"`
-	FromDepth int      `short:"F" long:"fromdepth" description:"List files from a certain depth." default:"-1"`
-	DirSearch []string `short:"d" long:"dirsearch" description:"Only include directories which have search terms as substrings. Can be used multiple times. Multiple values are inclusive by default. (OR) Does not work within archives."`
-	NoHide    bool     `short:"h" long:"hide" description:"Toggle of hiding of commonly unwanted files."`
-	MaxLimit  int      `short:"m" long:"max" description:"Maximum number of elements traversed in a single directory. Unlimited by default."`
-}
-
-type FilterOpts struct {
-	Search    []string `short:"s" long:"search" description:"Only include items which have search terms as substrings. Can be used multiple times. Multiple values are inclusive by default. (OR)"`
-	SearchAnd bool     `long:"AND" description:"Including this flag makes search with multiple values conjuctive, i.e., all search terms must be matched. (AND)"`
-	Include   []string `short:"i" long:"include" description:"File type inclusion. Can be used multiple times."`
-	Exclude   []string `short:"e" long:"exclude" description:"File type exclusion. Can be used multiple times."`
-	Ignore    []string `short:"I" long:"ignore" description:"Ignores all paths which include any given strings."`
-
-	DirOnly  bool `long:"dirs" description:"Only include directories in the result."`
-	FileOnly bool `long:"files" description:"Only include files in the result."`
-}
-
-type ProcessOpts struct {
-	Query     []string `short:"q" long:"query" description:"Fuzzy search query. Results will be ordered by their score."`
-	Ascending bool     `short:"a" long:"ascending" description:"Results will be ordered in ascending order. Files are ordered into descending order by default."`
-
-	Sort string `short:"S" long:"sort" description:"Sort the result by word." default:"none" choice:"none" choice:"name" choice:"n" choice:"mod" choice:"time" choice:"t" choice:"size" choice:"s" choice:"creation" choice:"c"`
-
-	// Mod      bool `long:"mod" description:"Results will be ordered by their modified time."`
-	// Size     bool `long:"size" description:"Results will be ordered by their size time."`
-	// None     bool `long:"none" description:"Results will be ordered by their modified time."`
-
-	Select []string `long:"select" description:"Select a single element or a range of elements. Usage: [{index}] [{from}:{to}] Supports negative indexing. Can be used without a flag as the last argument."`
-
-	Shuffle bool  `long:"shuffle" description:"Randomly shuffle the result."`
-	Seed    int64 `long:"seed" description:"Seed for the random shuffle." default:"-1"`
-}
-
-type Printing struct {
-	Absolute bool `short:"A" long:"absolute" description:"Format paths to be absolute. Relative by default."`
-	Debug    bool `short:"D" long:"debug" description:"Debug flag enables debug logging."`
-	Quiet    bool `short:"Q" long:"quiet" description:"Quiet flag disables printing results."`
-	Count    bool `short:"C" long:"count" description:"Print the number of results."`
-	Tree     bool `long:"tree" description:"Prints as tree."`
-}
-
-type Options struct {
-	ModeOpts    `group:"Mode options - Determines which mode list executes in, fs, string, file, etc."`
-	ListingOpts `group:"Traversal options - Determines how the traversal is done."`
-	FilterOpts  `group:"Filtering options - Applied while traversing, called on every entry found."`
-	ProcessOpts `group:"Processing options - Applied after traversal, called on the final list of files."`
-	Printing    `group:"Printing options - Determines how the results are printed."`
-
-	ExecArgs []string
-	Args     []string
-}
-
-func Recurse(opts *Options) {
-	opts.ToDepth = math.MaxInt64
-}
-
-func Parse(args []string) *Options {
-	var execArgs []string
-
-	if _, i := common.First(args, func(f string) bool { return f == "::" }); i != -1 {
-		// drop the "::", everything after goes to execargs
-		execArgs = args[i+1:]
-		args = args[:i]
-	}
-
-	opts := &Options{
-		ExecArgs: execArgs,
-	}
-	opts.MaxLimit = math.MaxInt64
-	rest, err := gf.ParseArgs(opts, args)
-	if err != nil {
-		if gf.WroteHelp(err) {
-			os.Exit(0)
-		}
-		log.Fatalln("Error parsing flags:", err)
-	}
-
-	opts.Args = rest
-
-	if opts.ToDepth == 0 && opts.Recurse {
-		Recurse(opts)
-	}
-
-	opts.ToDepth = common.Clamp(opts.ToDepth, opts.FromDepth+1, math.MaxInt64)
-
-	if opts.Debug {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
-	}
-
-	bef := len(opts.Args)
-	Implicit(opts)
-	if bef != len(opts.Args) {
-		slog.Debug("Found implicit commands", "len", bef-len(opts.Args))
-	}
-
-	return opts
-}
-
-func Implicit(opts *Options) {
-	if len(opts.Args) == 0 {
-		slog.Debug("implicit slice found no Args")
-		return
-	}
-
-	newArgs := make([]string, 0, len(opts.Args))
-	for _, arg := range opts.Args {
-
-		switch {
-		case len(arg) > 2 && arg[0] == '[' && arg[len(arg)-1] == ']':
-			opts.Select = append(opts.Select, arg)
-			slog.Debug("implicitly found cmd", "type", "Slice", "arg", arg)
-		case len(arg) > 1:
-			switch arg[0] {
-			case '?':
-				QuickCommand(arg[1:], opts)
-				slog.Debug("implicitly found cmd", "type", "QuickCommand", "arg", arg)
-			case '~':
-				opts.Query = append(opts.Query, arg[1:])
-				slog.Debug("implicitly found cmd", "type", "Query", "arg", arg)
-			case '=':
-				opts.Search = append(opts.Search, arg[1:])
-				slog.Debug("implicitly found cmd", "type", "Search", "arg", arg)
-			case '!':
-				opts.Ignore = append(opts.Ignore, arg[1:])
-				slog.Debug("implicitly found cmd", "type", "Ignore", "arg", arg)
-			default:
-				slog.Debug("implicit slice found no Args")
-				newArgs = append(newArgs, arg)
-			}
-		default:
-			slog.Debug("implicit slice found no Args")
-			newArgs = append(newArgs, arg)
-		}
-	}
-
-	opts.Args = newArgs
-}
-
-func QuickCommand(arg string, opts *Options) {
-	for _, r := range arg {
-		if fn, ok := pairs[r]; ok {
-			fn(opts)
-		}
-	}
-}
-
-func Do(args ...string) *Result {
-	opts := Parse(args)
-	return Run(opts)
-}
-
-var pairs = map[rune]func(*Options){
-	'm': func(opts *Options) { opts.Include = append(opts.Include, Audio, Video, Image) },
-	'a': func(opts *Options) { opts.Include = append(opts.Include, Audio) },
-	'v': func(opts *Options) { opts.Include = append(opts.Include, Video) },
-	'i': func(opts *Options) { opts.Include = append(opts.Include, Image) },
-	'h': func(opts *Options) { opts.NoHide = true },
-	'n': func(opts *Options) { opts.Sort = "name" },
-	'c': func(opts *Options) { opts.Sort = "creation" },
-	't': func(opts *Options) { opts.Sort = "time" },
-	'f': func(opts *Options) { opts.FileOnly = true },
-	'd': func(opts *Options) { opts.DirOnly = true },
-	'r': func(opts *Options) { Recurse(opts) },
-	'z': func(opts *Options) { opts.Archive = true },
-	'C': func(opts *Options) { opts.Count = true },
-	'R': func(opts *Options) { opts.Ascending = true },
-	'M': func(opts *Options) { opts.MaxLimit = 1000 },
-}
+package list
+
+import (
+	"log"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	gf "github.com/jessevdk/go-flags"
+	"github.com/periaate/common"
+)
+
+func Run(opts *Options) *Result {
+	res := &Result{Files: []*Finfo{}}
+	filters := InitFilters(CollectFilters(opts), res)
+	processes := CollectProcess(opts)
+	traverser := GetTraverser(opts)
+
+	traverser(opts, filters)
+	ProcessList(res, processes)
+	return res
+}
+
+func Initialize(opts *Options) (*Result, []Filter, []Process) {
+	res := &Result{Files: []*Finfo{}}
+	filters := CollectFilters(opts)
+	processes := CollectProcess(opts)
+
+	return res, filters, processes
+}
+
+type ModeOpts struct {
+	ArgMode   bool   `short:"l" long:"arg" description:"Skips listing, uses the input arguments as elements."`
+	FileMode  string `long:"file" description:"Reads the files given as arguments and uses either words or lines as elements." choice:"words" choice:"w" choice:"lines" choice:"l"`
+	NoConfig  bool   `long:"no-config" description:"Ignore the configuration files and their defaults and profiles."`
+	NullInput bool   `long:"null-input" description:"Piped arguments and the elements of --file are separated by NUL instead of newlines."`
+	Pick      bool   `long:"pick" description:"Pick files from the result interactively while it is traversed, then print them or use them with actions and ::. Tab marks multiple files."`
+
+	Watch      string        `long:"watch" description:"Watch the traversed directories, and print the result again or run the command after :: when it changes, or print the changes as events." optional:"yes" optional-value:"print" choice:"print" choice:"events"`
+	WatchDelay time.Duration `long:"watch-delay" description:"Wait for changes to stop for this long before printing." default:"200ms"`
+
+	Index string `long:"index" description:"Build or update the index of the directories given as arguments, list the indexes, or drop them. See --use-index." choice:"build" choice:"update" choice:"list" choice:"drop"`
+}
+
+type ListingOpts struct {
+	Recurse   bool     `short:"r" long:"recurse" description:"Recursively list files in subdirectories. Directory traversal is done iteratively and breadth first."`
+	Archive   bool     `short:"z" description:"Treat zip archives as directories. With --sniff, files without an extension are recognized as zip archives by their content."`
+	ToDepth   int      `short:"T" long:"todepth" description:"List files to a certain depth." default:"0"`
+	FromDepth int      `short:"F" long:"fromdepth" description:"List files from a certain depth." default:"-1"`
+	DirSearch []string `short:"d" long:"dirsearch" description:"Only include directories which have search terms as substrings. Can be used multiple times. Multiple values are inclusive by default. (OR) Does not work within archives."`
+	NoHide    bool     `short:"h" long:"hide" description:"Toggle of hiding of commonly unwanted files."`
+	MaxLimit  int      `short:"m" long:"max" description:"Maximum number of elements traversed in a single directory. Unlimited by default."`
+	DiskUsage bool     `long:"du" description:"Directories carry the total size and number of files beneath them. Traverses past --todepth to compute the totals."`
+
+	UseIndex    bool          `long:"use-index" description:"Read directories from the index built with --index instead of the disk. Directories without an index are read from disk."`
+	IndexMaxAge time.Duration `long:"index-max-age" description:"Refresh an index older than this before using it. Only directories whose modification time changed are read again."`
+	IndexVerify bool          `long:"index-verify" description:"Check the modification time of every indexed directory while traversing, reading and updating changed ones."`
+}
+
+type FilterOpts struct {
+	Search    []string `short:"s" long:"search" description:"Only include items which have search terms as substrings. Can be used multiple times. Multiple values are inclusive by default. (OR)"`
+	SearchAnd bool     `long:"AND" description:"Including this flag makes search with multiple values conjuctive, i.e., all search terms must be matched. (AND)"`
+	Include   []string `short:"i" long:"include" description:"File type inclusion. Can be used multiple times."`
+	Exclude   []string `short:"e" long:"exclude" description:"File type exclusion. Can be used multiple times."`
+	Ignore    []string `short:"I" long:"ignore" description:"Ignores all paths which include any given strings."`
+	Sniff     bool     `long:"sniff" description:"Detect file types from their content instead of only their extension. Requires reading every file."`
+
+	DirOnly  bool `long:"dirs" description:"Only include directories in the result."`
+	FileOnly bool `long:"files" description:"Only include files in the result."`
+
+	Contains    []string `long:"contains" description:"Only include files whose content contains the pattern. Can be used multiple times. (OR) Binary files are skipped."`
+	Regex       bool     `long:"regex" description:"Treat --contains patterns as regular expressions."`
+	LineNumber  bool     `short:"n" long:"line-number" description:"Print the line numbers matched by --contains."`
+	ContentMax  int64    `long:"content-max" description:"Files larger than this many bytes are not searched by --contains." default:"16777216"`
+	ContentJobs int      `long:"content-jobs" description:"Number of files searched by --contains concurrently." default:"8"`
+}
+
+type ProcessOpts struct {
+	Query     []string `short:"q" long:"query" description:"Fuzzy search query. Results will be ordered by their score."`
+	Ascending bool     `short:"a" long:"ascending" description:"Results will be ordered in ascending order. Files are ordered into descending order by default."`
+
+	Sort string `short:"S" long:"sort" description:"Sort the result by word." default:"none" choice:"none" choice:"name" choice:"n" choice:"mod" choice:"time" choice:"t" choice:"size" choice:"s" choice:"creation" choice:"c"`
+
+	// Mod      bool `long:"mod" description:"Results will be ordered by their modified time."`
+	// Size     bool `long:"size" description:"Results will be ordered by their size time."`
+	// None     bool `long:"none" description:"Results will be ordered by their modified time."`
+
+	Select []string `long:"select" description:"Select a single element or a range of elements. Usage: [{index}] [{from}:{to}] Supports negative indexing. Can be used without a flag as the last argument."`
+
+	Shuffle bool  `long:"shuffle" description:"Randomly shuffle the result."`
+	Seed    int64 `long:"seed" description:"Seed for the random shuffle." default:"-1"`
+
+	Dupes     bool   `long:"dupes" description:"Only include files which have identical content to another file. Duplicates are printed in groups separated by empty lines, or one after another with -0."`
+	DupesHash string `long:"dupes-hash" description:"Hash used for finding duplicates." default:"xxhash" choice:"xxhash" choice:"sha256"`
+	Keep      string `long:"keep" description:"Keep one file of each group of duplicates and only print the rest, e.g., for deletion." choice:"oldest" choice:"newest" choice:"shortest"`
+	HashJobs  int    `long:"hash-jobs" description:"Number of files hashed concurrently." default:"8"`
+
+	Hash   string `long:"hash" description:"Print the digest of each file in sha256sum compatible format. Directories and unreadable files are dropped. Files are hashed after --select." choice:"sha256" choice:"md5" choice:"blake2b" choice:"crc32" choice:"xxhash"`
+	Verify string `long:"verify" description:"Report files which are missing, changed or new compared to a manifest created with --hash. Use the same traversal options as when creating it."`
+}
+
+type Printing struct {
+	Absolute  bool   `short:"A" long:"absolute" description:"Format paths to be absolute. Relative by default."`
+	Debug     bool   `short:"D" long:"debug" description:"Debug flag enables debug logging."`
+	Quiet     bool   `short:"Q" long:"quiet" description:"Quiet flag disables printing results."`
+	Count     bool   `short:"C" long:"count" description:"Print the number of results."`
+	Print0    bool   `short:"0" long:"print0" description:"Separate printed results with NUL instead of newlines."`
+	Long      bool   `short:"L" long:"long" description:"Print an ls -l style listing with permissions, links, owner, group, size and modification time."`
+	Clipboard string `short:"c" long:"clipboard" description:"Copy the result to the clipboard, either its paths, absolute paths, or the output as printed." optional:"yes" optional-value:"paths" choice:"paths" choice:"absolute" choice:"output"`
+	Color     string `long:"color" description:"Color paths by file type and kind, using LS_COLORS. Auto colors when printing to a terminal and NO_COLOR is not set." default:"auto" choice:"auto" choice:"always" choice:"never"`
+	Tree      bool   `long:"tree" description:"Prints as tree."`
+	Human     bool   `short:"H" long:"human" description:"Print sizes in human readable units."`
+	Format    string `long:"format" description:"Print the files in the given format instead of their paths. Either json, ndjson, or a Go template over each file, followed by a newline."`
+	Printf    string `long:"printf" description:"Print each file with a Go template, without adding a newline. Supports \\t, \\n and \\0 escapes."`
+
+	TreeDepth int      `long:"tree-depth" description:"Only print the tree to a certain depth below its roots. Unlimited by default."`
+	TreeInfo  []string `long:"tree-info" description:"Print a column before the names in the tree. Can be used multiple times. Size and count by default with --du." choice:"size" choice:"count" choice:"date"`
+	Collapse  bool     `long:"collapse" description:"Collapse directories in the tree which only contain a single directory."`
+}
+
+type ExecOpts struct {
+	ExecEach  bool `short:"x" long:"exec-each" description:"Run the command once per file. Default when the command contains placeholders: {} {/} {//} {.} {/.}"`
+	ExecBatch bool `short:"X" long:"exec-batch" description:"Run the command with many files at once, even if it contains placeholders. A lone {} is replaced by the paths."`
+	ExecMax   int  `long:"exec-max" description:"Maximum number of files given to a single batched command. Batches are also split to fit the argument size limit."`
+	ExecJobs  int  `short:"j" long:"exec-jobs" description:"Number of commands run in parallel. The output of each command is written at once when it finishes." default:"1"`
+	KeepOrder bool `long:"keep-order" description:"Write the output of parallel commands in the order of the files."`
+	Halt      bool `long:"halt" description:"Stop starting new commands after one fails."`
+	DryRun    bool `long:"dry-run" description:"Print the command lines or file operations which would be run, quoted for the shell, instead of running them."`
+}
+
+type ActionOpts struct {
+	MoveTo     string `long:"move-to" description:"Move the files into the directory, keeping their structure beneath the traversed directories."`
+	CopyTo     string `long:"copy-to" description:"Copy the files into the directory, keeping their structure beneath the traversed directories. Archive entries are extracted."`
+	SymlinkTo  string `long:"symlink-to" description:"Create symlinks to the files in the directory, keeping their structure beneath the traversed directories."`
+	HardlinkTo string `long:"hardlink-to" description:"Create hard links to the files in the directory, keeping their structure beneath the traversed directories."`
+	Trash      bool   `long:"trash" description:"Move the files to the trash."`
+	Delete     bool   `long:"delete" description:"Delete the files, and directories with everything beneath them."`
+	Rename     bool   `long:"rename" description:"Edit the paths of the files in $EDITOR and rename them to the edited paths, like vidir."`
+
+	RenamePattern string `long:"rename-pattern" description:"Rename the files in the order of the result to a pattern, e.g., {parent}_{n:03}{ext}. See the readme for the fields."`
+	RenameRegex   string `long:"rename-regex" description:"Regular expression matched against the names for --rename-pattern, whose groups are used as {1} or by their names. Files which do not match are not renamed."`
+	RenameStart   int    `long:"rename-start" description:"First number of the counters of --rename-pattern." default:"1"`
+
+	Undo        string `long:"undo" description:"Undo the last action, or the one with the given id, if its files have not changed since. Deletes can not be undone." optional:"yes" optional-value:"last"`
+	ShowJournal bool   `long:"journal" description:"Print the id, time and command of the actions which can be undone."`
+
+	Conflict string `long:"conflict" description:"What to do when the destination exists. Files of the result with the same destination are never overwritten by each other, only renamed." default:"skip" choice:"skip" choice:"overwrite" choice:"rename"`
+	Flat     bool   `long:"flat" description:"Put the files directly into the destination instead of keeping their structure."`
+}
+
+type Options struct {
+	ModeOpts    `group:"Mode options - Determines which mode list executes in, fs, string, file, etc."`
+	ListingOpts `group:"Traversal options - Determines how the traversal is done."`
+	FilterOpts  `group:"Filtering options - Applied while traversing, called on every entry found."`
+	ProcessOpts `group:"Processing options - Applied after traversal, called on the final list of files."`
+	Printing    `group:"Printing options - Determines how the results are printed."`
+	ExecOpts    `group:"Exec options - Determines how the command given after :: is run on the result."`
+	ActionOpts  `group:"Action options - File operations done on the result instead of printing it."`
+
+	ExecArgs []string
+	Args     []string
+}
+
+func Recurse(opts *Options) {
+	opts.ToDepth = math.MaxInt64
+}
+
+func Parse(args []string) *Options {
+	var execArgs []string
+
+	if i := slices.Index(args, "::"); i != -1 {
+		// drop the "::", everything after goes to execargs
+		execArgs = args[i+1:]
+		args = args[:i]
+	}
+
+	args = ExpandQuick(applyConfig(args))
+
+	opts := &Options{
+		ExecArgs: execArgs,
+	}
+	opts.MaxLimit = math.MaxInt64
+	rest, err := gf.ParseArgs(opts, args)
+	if err != nil {
+		if gf.WroteHelp(err) {
+			os.Exit(0)
+		}
+		log.Fatalln("Error parsing flags:", err)
+	}
+
+	opts.Args = rest
+
+	if _, err := GetMatcher(opts); err != nil {
+		log.Fatalln("Error parsing flags:", err)
+	}
+
+	if opts.ToDepth == 0 && opts.Recurse {
+		Recurse(opts)
+	}
+
+	opts.ToDepth = common.Clamp(opts.ToDepth, opts.FromDepth+1, math.MaxInt64)
+
+	if opts.Debug {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+
+	bef := len(opts.Args)
+	Implicit(opts)
+	if bef != len(opts.Args) {
+		slog.Debug("Found implicit commands", "len", bef-len(opts.Args))
+	}
+
+	return opts
+}
+
+func Implicit(opts *Options) {
+	if len(opts.Args) == 0 {
+		slog.Debug("implicit slice found no Args")
+		return
+	}
+
+	newArgs := make([]string, 0, len(opts.Args))
+	for _, arg := range opts.Args {
+
+		switch {
+		case len(arg) > 2 && arg[0] == '[' && arg[len(arg)-1] == ']':
+			opts.Select = append(opts.Select, arg)
+			slog.Debug("implicitly found cmd", "type", "Slice", "arg", arg)
+		case len(arg) > 1:
+			switch arg[0] {
+			case '~':
+				opts.Query = append(opts.Query, arg[1:])
+				slog.Debug("implicitly found cmd", "type", "Query", "arg", arg)
+			case '=':
+				opts.Search = append(opts.Search, arg[1:])
+				slog.Debug("implicitly found cmd", "type", "Search", "arg", arg)
+			case '!':
+				opts.Ignore = append(opts.Ignore, arg[1:])
+				slog.Debug("implicitly found cmd", "type", "Ignore", "arg", arg)
+			default:
+				slog.Debug("implicit slice found no Args")
+				newArgs = append(newArgs, arg)
+			}
+		default:
+			slog.Debug("implicit slice found no Args")
+			newArgs = append(newArgs, arg)
+		}
+	}
+
+	opts.Args = newArgs
+}
+
+func Do(args ...string) *Result {
+	opts := Parse(args)
+	return Run(opts)
+}
@@ -0,0 +1,170 @@
+package list
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// halfYear is the age after which long listings show the year instead of the time of day, like ls.
+const halfYear = 182 * 24 * time.Hour
+
+// LongRow is the columns of a file in a long listing.
+type LongRow struct {
+	Mode   string
+	Links  string
+	Owner  string
+	Group  string
+	Size   string
+	Time   string
+	Path   string
+	Target string // target of a symlink
+}
+
+func NewLongRow(fi *Finfo, opts *Options) LongRow {
+	row := LongRow{Path: FormatPath(fi, opts), Links: "1", Owner: "-", Group: "-"}
+
+	info := fi.Info
+	if info == nil {
+		var err error
+		if info, err = os.Lstat(fi.Path); err != nil {
+			row.Mode, row.Size, row.Time = "?", "?", "?"
+			return row
+		}
+	}
+
+	row.Mode = FormatMode(info.Mode())
+	row.Size = FormatSize(info.Size(), opts)
+	if fi.IsDir && opts.DiskUsage {
+		row.Size = FormatSize(fi.Total, opts)
+	}
+	row.Time = formatLongTime(info.ModTime())
+
+	owner := info
+	if owner.Sys() == nil {
+		// indexed files only have the stat data of the index, read the owner from the file itself
+		if st, err := os.Lstat(fi.Path); err == nil {
+			owner = st
+		}
+	}
+	if nlink, uid, gid, ok := statOwner(owner); ok {
+		row.Links = strconv.FormatUint(nlink, 10)
+		row.Owner = lookupUser(uid)
+		row.Group = lookupGroup(gid)
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		row.Target, _ = os.Readlink(fi.Path)
+	}
+	return row
+}
+
+// FormatMode formats the mode like ls, e.g., drwxr-xr-x.
+func FormatMode(m fs.FileMode) string {
+	b := []byte("-rwxrwxrwx")
+	switch {
+	case m&fs.ModeDir != 0:
+		b[0] = 'd'
+	case m&fs.ModeSymlink != 0:
+		b[0] = 'l'
+	case m&fs.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case m&fs.ModeSocket != 0:
+		b[0] = 's'
+	case m&fs.ModeCharDevice != 0:
+		b[0] = 'c'
+	case m&fs.ModeDevice != 0:
+		b[0] = 'b'
+	}
+	for i := 0; i < 9; i++ {
+		if m&(1<<uint(8-i)) == 0 {
+			b[i+1] = '-'
+		}
+	}
+	special := func(i int, set bool, lower byte) {
+		switch {
+		case !set:
+		case b[i] == '-':
+			b[i] = lower - 'a' + 'A'
+		default:
+			b[i] = lower
+		}
+	}
+	special(3, m&fs.ModeSetuid != 0, 's')
+	special(6, m&fs.ModeSetgid != 0, 's')
+	special(9, m&fs.ModeSticky != 0, 't')
+	return string(b)
+}
+
+func formatLongTime(t time.Time) string {
+	if d := time.Since(t); d > halfYear || d < -halfYear {
+		return t.Format("Jan _2  2006")
+	}
+	return t.Format("Jan _2 15:04")
+}
+
+var (
+	users  sync.Map
+	groups sync.Map
+)
+
+func lookupUser(uid string) string {
+	if name, ok := users.Load(uid); ok {
+		return name.(string)
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	users.Store(uid, name)
+	return name
+}
+
+func lookupGroup(gid string) string {
+	if name, ok := groups.Load(gid); ok {
+		return name.(string)
+	}
+	name := gid
+	if g, err := user.LookupGroupId(gid); err == nil {
+		name = g.Name
+	}
+	groups.Store(gid, name)
+	return name
+}
+
+// PrintLong writes the files as an ls -l style listing, with the columns aligned across all files.
+func PrintLong(w *bufio.Writer, els []*Finfo, opts *Options) {
+	rows := make([]LongRow, len(els))
+	var links, owner, group, size int
+	for i, fi := range els {
+		rows[i] = NewLongRow(fi, opts)
+		links = max(links, len(rows[i].Links))
+		owner = max(owner, len(rows[i].Owner))
+		group = max(group, len(rows[i].Group))
+		size = max(size, len(rows[i].Size))
+	}
+
+	colors := NewColors(opts)
+	end := Terminator(opts)
+	for i, row := range rows {
+		w.WriteString(row.Mode + " ")
+		w.WriteString(padLeft(row.Links, links) + " ")
+		w.WriteString(padRight(row.Owner, owner) + " ")
+		w.WriteString(padRight(row.Group, group) + " ")
+		w.WriteString(padLeft(row.Size, size) + " ")
+		w.WriteString(row.Time + " ")
+		w.WriteString(colors.Paint(row.Path, els[i]))
+		if row.Target != "" {
+			w.WriteString(" -> " + row.Target)
+		}
+		w.WriteString(end)
+	}
+}
+
+func padLeft(s string, n int) string  { return strings.Repeat(" ", max(n-len(s), 0)) + s }
+func padRight(s string, n int) string { return s + strings.Repeat(" ", max(n-len(s), 0)) }
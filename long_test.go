@@ -0,0 +1,58 @@
+package list
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFormatMode(t *testing.T) {
+	tests := []struct {
+		mode fs.FileMode
+		want string
+	}{
+		{0o644, "-rw-r--r--"},
+		{fs.ModeDir | 0o755, "drwxr-xr-x"},
+		{fs.ModeSymlink | 0o777, "lrwxrwxrwx"},
+		{fs.ModeNamedPipe | 0o600, "prw-------"},
+		{fs.ModeDevice | fs.ModeCharDevice | 0o620, "crw--w----"},
+		{fs.ModeDevice | 0o660, "brw-rw----"},
+		{fs.ModeSetuid | 0o755, "-rwsr-xr-x"},
+		{fs.ModeSetuid | 0o644, "-rwSr--r--"},
+		{fs.ModeSetgid | 0o750, "-rwxr-s---"},
+		{fs.ModeDir | fs.ModeSticky | 0o777, "drwxrwxrwt"},
+		{fs.ModeDir | fs.ModeSticky | 0o776, "drwxrwxrwT"},
+	}
+	for _, tt := range tests {
+		if got := FormatMode(tt.mode); got != tt.want {
+			t.Errorf("FormatMode(%v) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestLongRowIndexed(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"f": ""})
+	path := filepath.Join(dir, "f")
+	if err := os.Link(path, filepath.Join(dir, "g")); err != nil {
+		t.Skip(err)
+	}
+	st, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the index has no owner or link count, they are read from the file
+	e := IndexEntry{Name: st.Name(), Size: st.Size(), Mode: st.Mode(), ModTime: st.ModTime()}
+	opts := &Options{}
+	got := NewLongRow(&Finfo{Name: "f", Path: path, Info: indexInfo{e}}, opts)
+	want := NewLongRow(&Finfo{Name: "f", Path: path, Info: st}, opts)
+	if got != want {
+		t.Errorf("got %+v from the index, want %+v", got, want)
+	}
+	if runtime.GOOS != "windows" && got.Links != "2" {
+		t.Errorf("got %s links, want 2", got.Links)
+	}
+}
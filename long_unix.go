@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package list
+
+import (
+	"io/fs"
+	"strconv"
+	"syscall"
+)
+
+// statOwner returns the link count and owner ids of the file, if available.
+func statOwner(info fs.FileInfo) (nlink uint64, uid, gid string, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	return uint64(st.Nlink), strconv.FormatUint(uint64(st.Uid), 10), strconv.FormatUint(uint64(st.Gid), 10), true
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package list
+
+import (
+	"io/fs"
+)
+
+// Windows does not expose owners through fs.FileInfo, long listings show "-" instead.
+func statOwner(_ fs.FileInfo) (nlink uint64, uid, gid string, ok bool) { return }
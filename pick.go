@@ -0,0 +1,547 @@
+package list
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pickFrame is how often the picker redraws while files are still being found.
+const pickFrame = 50 * time.Millisecond
+
+// Pick lets the user pick files from the result on the terminal, while it is still being traversed.
+// Typing ranks the files with the query scorer, initially with the --query. The picked files are
+// returned for printing, actions or exec, along with the exit status: 1 if nothing was picked and
+// 130 if the picker was cancelled.
+func Pick(opts *Options) (*Result, int) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "--pick needs a terminal:", err)
+		return nil, 1
+	}
+	defer tty.Close()
+
+	restore, err := rawMode(tty)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "--pick needs a terminal:", err)
+		return nil, 1
+	}
+
+	p := newPicker(opts, tty)
+	go p.traverse()
+
+	p.out.WriteString("\x1b[?1049h")
+	files, status := p.run()
+	p.out.WriteString("\x1b[?1049l")
+	p.out.Flush()
+	restore()
+
+	return &Result{Files: files}, status
+}
+
+type picker struct {
+	opts   *Options
+	tty    *os.File
+	out    *bufio.Writer
+	colors *Colors
+
+	mu      sync.Mutex
+	files   []*Finfo          // found so far, then processed copies of them in the order of the result once done
+	origs   map[*Finfo]*Finfo // the found files of the processed copies, until the marks are moved to them
+	done    bool
+	changed chan struct{}
+
+	live []Process // processes dropping single files, applied to every file as it is found
+
+	query  []rune
+	items  []*Finfo // files matching the query, ranked
+	marked map[*Finfo]int
+	marks  int
+	cursor int
+	offset int
+	width  int
+	height int
+
+	previewPath  string
+	previewLines []string
+}
+
+func newPicker(opts *Options, tty *os.File) *picker {
+	p := &picker{
+		opts:    opts,
+		tty:     tty,
+		out:     bufio.NewWriter(tty),
+		changed: make(chan struct{}, 1),
+		query:   []rune(strings.Join(opts.Query, " ")),
+		marked:  map[*Finfo]int{},
+	}
+	if opts.Color != "never" && os.Getenv("NO_COLOR") == "" {
+		p.colors = ParseLsColors(os.Getenv("LS_COLORS"))
+	}
+
+	// the picker ranks by the query itself, and searches the contents of every file as it is found.
+	// Other processes order the result once it is traversed.
+	opts.Query = nil
+	if len(opts.Contains) > 0 {
+		p.live = append(p.live, ContainsProcess(opts))
+		opts.Contains = nil
+	}
+	if opts.Hash != "" {
+		p.live = append(p.live, DropDirs)
+	}
+	return p
+}
+
+// traverse finds the files, and orders them with the processes once all are found.
+func (p *picker) traverse() {
+	filters := CollectFilters(p.opts)
+	processes := CollectProcess(p.opts)
+
+	// the live processes may read the files, so they are run concurrently
+	sem := make(chan struct{}, max(p.opts.ContentJobs, 1))
+	var wg sync.WaitGroup
+	GetTraverser(p.opts)(p.opts, func(fi *Finfo) {
+		for _, fn := range filters {
+			if !fn(fi) {
+				return
+			}
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			for _, fn := range p.live {
+				if len(fn([]*Finfo{fi})) == 0 {
+					return
+				}
+			}
+			p.mu.Lock()
+			p.files = append(p.files, fi)
+			p.mu.Unlock()
+			p.notify()
+		}()
+	})
+	wg.Wait()
+
+	// the processes run on copies of the files, as they set fields of them which are drawn meanwhile
+	p.mu.Lock()
+	files := make([]*Finfo, len(p.files))
+	origs := make(map[*Finfo]*Finfo, len(p.files))
+	for i, fi := range p.files {
+		c := *fi
+		files[i], origs[&c] = &c, fi
+	}
+	p.mu.Unlock()
+	res := &Result{Files: files}
+	ProcessList(res, processes)
+
+	p.mu.Lock()
+	p.files, p.origs, p.done = res.Files, origs, true
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *picker) notify() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// run handles keys until files are picked or the picker is cancelled.
+func (p *picker) run() ([]*Finfo, int) {
+	keys, stop := make(chan string), make(chan struct{})
+	defer close(stop)
+	go readKeys(p.tty, keys, stop)
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+	tick := time.NewTicker(pickFrame)
+	defer tick.Stop()
+
+	p.resize()
+	p.rank()
+	p.draw()
+
+	var dirty bool
+	for {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil, 130
+			}
+			if files, status, end := p.key(k); end {
+				return files, status
+			}
+			p.draw()
+		case <-p.changed:
+			dirty = true
+		case <-resize:
+			p.resize()
+			p.draw()
+		case <-tick.C:
+			if dirty {
+				p.rank()
+				p.draw()
+				dirty = false
+			}
+		}
+	}
+}
+
+// key handles a key, returning the picked files and true when the picker ends.
+func (p *picker) key(k string) ([]*Finfo, int, bool) {
+	switch k {
+	case "\r":
+		return p.picked()
+	case "\x1b", "\x03", "\x07":
+		return nil, 130, true
+	case "\x04":
+		if len(p.query) == 0 {
+			return nil, 130, true
+		}
+	case "\x1b[A", "\x1bOA", "\x10", "\x0b":
+		p.move(-1)
+	case "\x1b[B", "\x1bOB", "\x0e", "\n":
+		p.move(1)
+	case "\x1b[5~":
+		p.move(-p.listHeight())
+	case "\x1b[6~":
+		p.move(p.listHeight())
+	case "\t":
+		p.toggle()
+		p.move(1)
+	case "\x1b[Z":
+		p.toggle()
+		p.move(-1)
+	case "\x01":
+		p.toggleAll()
+	case "\x7f", "\x08":
+		if len(p.query) > 0 {
+			p.setQuery(p.query[:len(p.query)-1])
+		}
+	case "\x15":
+		p.setQuery(nil)
+	case "\x17":
+		q := strings.TrimRightFunc(string(p.query), unicode.IsSpace)
+		q = q[:strings.LastIndexFunc(q, unicode.IsSpace)+1]
+		p.setQuery([]rune(q))
+	default:
+		if r, _ := utf8.DecodeRuneInString(k); r >= ' ' && r != utf8.RuneError && !strings.HasPrefix(k, "\x1b") {
+			p.setQuery(append(p.query, []rune(k)...))
+		}
+	}
+	return nil, 0, false
+}
+
+// picked returns the marked files in the order they were marked, or the file under the cursor.
+func (p *picker) picked() ([]*Finfo, int, bool) {
+	if len(p.marked) == 0 {
+		if p.cursor >= len(p.items) {
+			return nil, 1, true
+		}
+		return []*Finfo{p.items[p.cursor]}, 0, true
+	}
+
+	files := make([]*Finfo, 0, len(p.marked))
+	for fi := range p.marked {
+		files = append(files, fi)
+	}
+	sort.Slice(files, func(i, j int) bool { return p.marked[files[i]] < p.marked[files[j]] })
+	return files, 0, true
+}
+
+// setQuery ranks the files by the new query, moving the cursor to the best match.
+func (p *picker) setQuery(q []rune) {
+	p.query = q
+	p.items, p.cursor, p.offset = nil, 0, 0
+	p.rank()
+}
+
+func (p *picker) move(n int) {
+	p.cursor = max(min(p.cursor+n, len(p.items)-1), 0)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if h := p.listHeight(); p.cursor >= p.offset+h {
+		p.offset = p.cursor - h + 1
+	}
+}
+
+func (p *picker) toggle() {
+	if p.cursor >= len(p.items) {
+		return
+	}
+	fi := p.items[p.cursor]
+	if _, ok := p.marked[fi]; ok {
+		delete(p.marked, fi)
+		return
+	}
+	p.marks++
+	p.marked[fi] = p.marks
+}
+
+// toggleAll marks every matching file, or unmarks them if all are marked.
+func (p *picker) toggleAll() {
+	all := true
+	for _, fi := range p.items {
+		if _, ok := p.marked[fi]; !ok {
+			all = false
+			break
+		}
+	}
+	for _, fi := range p.items {
+		switch _, ok := p.marked[fi]; {
+		case all:
+			delete(p.marked, fi)
+		case !ok:
+			p.marks++
+			p.marked[fi] = p.marks
+		}
+	}
+}
+
+// rank scores the found files against the query, keeping the file under the cursor selected.
+// Queries shorter than the n-grams of the scorer match the names which contain them.
+func (p *picker) rank() {
+	var current *Finfo
+	if p.cursor < len(p.items) {
+		current = p.items[p.cursor]
+	}
+
+	p.mu.Lock()
+	files, origs := p.files, p.origs
+	p.origs = nil
+	p.mu.Unlock()
+	if origs != nil {
+		current = p.replace(files, origs, current)
+	}
+
+	q := strings.ToLower(strings.TrimSpace(string(p.query)))
+	if q == "" {
+		p.items = files
+	} else {
+		words := strings.Fields(q)
+		grams, _ := GenNgrams(words, N)
+		scorer := GetScoringFunction(words)
+		scorable := make(ScoredFiles[*Finfo], 0, len(files))
+		for _, fi := range files {
+			var score float32
+			switch {
+			case len(grams) != 0:
+				score = scorer(fi.Name)
+			case strings.Contains(strings.ToLower(fi.Name), q):
+				score = 1
+			}
+			if score > 0 {
+				scorable = append(scorable, scored[*Finfo]{fi, score})
+			}
+		}
+		sort.SliceStable(scorable, func(i, j int) bool { return scorable[i].score > scorable[j].score })
+		p.items = scorable.Items()
+	}
+
+	if current != nil && (p.cursor >= len(p.items) || p.items[p.cursor] != current) {
+		for i, fi := range p.items {
+			if fi == current {
+				p.cursor = i
+				break
+			}
+		}
+	}
+	p.move(0)
+}
+
+// replace moves the marks to the processed copies of the files, dropping the marks of files not in the result.
+// It returns the copy of the file under the cursor.
+func (p *picker) replace(files []*Finfo, origs map[*Finfo]*Finfo, current *Finfo) *Finfo {
+	marked := make(map[*Finfo]int, len(p.marked))
+	var copied *Finfo
+	for _, fi := range files {
+		if n, ok := p.marked[origs[fi]]; ok {
+			marked[fi] = n
+		}
+		if current != nil && origs[fi] == current {
+			copied = fi
+		}
+	}
+	p.marked = marked
+	return copied
+}
+
+func (p *picker) resize() {
+	w, h, err := termSize(p.tty)
+	if err != nil || w <= 0 || h <= 0 {
+		w, h = 80, 24
+	}
+	p.width, p.height = w, h
+	p.move(0)
+}
+
+// listHeight is the number of rows of files, below the prompt and the status line.
+func (p *picker) listHeight() int { return max(p.height-2, 1) }
+
+// draw writes the prompt, the status line and the files, with a preview of the file under the cursor
+// to their right if the terminal is wide enough.
+func (p *picker) draw() {
+	p.mu.Lock()
+	found, done := len(p.files), p.done
+	p.mu.Unlock()
+
+	listW, previewW := p.width, 0
+	if p.width >= 100 {
+		listW = p.width / 2
+		previewW = p.width - listW - 1
+	}
+
+	w := p.out
+	w.WriteString("\x1b[?25l\x1b[H")
+	w.WriteString(truncate("> "+string(p.query), p.width) + "\x1b[K\r\n")
+
+	status := fmt.Sprintf("  %d/%d", len(p.items), found)
+	if len(p.marked) > 0 {
+		status += fmt.Sprintf(" (%d marked)", len(p.marked))
+	}
+	if !done {
+		status += " ..."
+	}
+	w.WriteString("\x1b[2m" + truncate(status, p.width) + "\x1b[0m\x1b[K")
+
+	var preview []string
+	if previewW > 0 {
+		preview = p.preview()
+	}
+
+	for row := 0; row < p.listHeight(); row++ {
+		w.WriteString("\r\n")
+		if i := p.offset + row; i < len(p.items) {
+			w.WriteString(p.item(i, listW))
+		}
+		if previewW > 0 {
+			fmt.Fprintf(w, "\x1b[%dG\x1b[2m│\x1b[0m", listW+1)
+			if row < len(preview) {
+				w.WriteString(truncate(preview[row], previewW))
+			}
+		}
+		w.WriteString("\x1b[K")
+	}
+
+	fmt.Fprintf(w, "\x1b[1;%dH\x1b[?25h", min(3+len(p.query), p.width))
+	w.Flush()
+}
+
+// item formats the file at i, padded to width so the cursor line is highlighted across it.
+func (p *picker) item(i, width int) string {
+	fi := p.items[i]
+	mark := "  "
+	if _, ok := p.marked[fi]; ok {
+		mark = "* "
+	}
+	path := FormatPath(fi, p.opts)
+	if fi.IsDir {
+		path += "/"
+	}
+	text := truncate(mark+path, width)
+
+	if i == p.cursor {
+		return "\x1b[7m" + text + strings.Repeat(" ", max(width-utf8.RuneCountInString(text), 0)) + "\x1b[0m"
+	}
+	return mark + p.colors.Paint(strings.TrimPrefix(text, mark), fi)
+}
+
+// preview returns the lines shown for the file under the cursor: the contents of a directory,
+// or the kinds, size and modification time of a file.
+func (p *picker) preview() []string {
+	if p.cursor >= len(p.items) {
+		return nil
+	}
+	fi := p.items[p.cursor]
+	if fi.Path == p.previewPath {
+		return p.previewLines
+	}
+	p.previewPath = fi.Path
+
+	lines := []string{" " + fi.Path}
+	switch {
+	case fi.IsDir && fi.Archive == "":
+		entries, err := os.ReadDir(fi.Path)
+		if err != nil {
+			lines = append(lines, " "+err.Error())
+			break
+		}
+		lines[0] += fmt.Sprintf(" (%d)", len(entries))
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				name += string(filepath.Separator)
+			}
+			lines = append(lines, " "+name)
+		}
+	default:
+		if kinds := KindNames(fi.Mask); len(kinds) > 0 {
+			lines = append(lines, " "+strings.Join(kinds, ", "))
+		}
+		lines = append(lines, " "+HumanSize(fi.Size), " "+fi.ModTime.Format(time.DateTime))
+	}
+	p.previewLines = lines
+	return lines
+}
+
+// readKeys sends the keys read from the terminal, an escape sequence or a character at a time,
+// and closes keys when it can not be read anymore. It returns when stop is closed, without sending
+// the keys read since, and when the terminal is closed.
+func readKeys(tty *os.File, keys chan<- string, stop <-chan struct{}) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := tty.Read(buf)
+		if err != nil {
+			return
+		}
+		for b := buf[:n]; len(b) > 0; {
+			size := keyLen(b)
+			select {
+			case keys <- string(b[:size]):
+			case <-stop:
+				return
+			}
+			b = b[size:]
+		}
+	}
+}
+
+// keyLen returns the length of the key at the start of b.
+func keyLen(b []byte) int {
+	if b[0] != '\x1b' || len(b) == 1 {
+		_, size := utf8.DecodeRune(b)
+		return size
+	}
+	switch b[1] {
+	case '[':
+		// CSI sequences end in a byte from @ to ~
+		for i := 2; i < len(b); i++ {
+			if b[i] >= '@' && b[i] <= '~' {
+				return i + 1
+			}
+		}
+		return len(b)
+	case 'O':
+		return min(3, len(b))
+	}
+	return 1
+}
+
+// truncate cuts s to at most n runes.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	rs := []rune(s)
+	return string(rs[:max(n, 0)])
+}
@@ -0,0 +1,46 @@
+package list
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPickerTraverse(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "foo", "b.txt": "bar", "c.txt": "foo bar"})
+
+	opts := &Options{Args: []string{dir}}
+	opts.MaxLimit = math.MaxInt64
+	opts.Contains = []string{"foo"}
+	opts.Hash = "crc32"
+	opts.Sort = "name"
+	p := newPicker(opts, nil)
+
+	// mark a found file before the marks are moved to the processed copies
+	found := map[string]*Finfo{}
+	p.traverse()
+	for _, fi := range p.origs {
+		found[fi.Name] = fi
+	}
+	p.marked[found["c.txt"]] = 1
+	p.rank()
+
+	var names []string
+	for _, fi := range p.files {
+		if fi.Hash == "" {
+			t.Errorf("%s was not hashed", fi.Name)
+		}
+		if found[fi.Name] == fi || found[fi.Name].Hash != "" {
+			t.Errorf("%s was processed in place instead of a copy", fi.Name)
+		}
+		names = append(names, fi.Name)
+	}
+	if len(names) != 2 || names[0] != "a.txt" || names[1] != "c.txt" {
+		t.Errorf("got %v, want [a.txt c.txt]", names)
+	}
+
+	picked, status, _ := p.picked()
+	if status != 0 || len(picked) != 1 || picked[0].Name != "c.txt" || picked[0].Hash == "" {
+		t.Errorf("got %v, want the processed c.txt", picked)
+	}
+}
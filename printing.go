@@ -1,114 +1,116 @@
-package list
-
-import (
-	"bufio"
-	"fmt"
-	"log/slog"
-	"os"
-	"path/filepath"
-	"sort"
-	"strings"
-)
-
-// the interval of flushing the buffer
-const bufLength = 500
-
-func PrintWithBuf(els []*Finfo, opts *Options) {
-	if opts.Count {
-		fmt.Println(len(els))
-		return
-	}
-
-	if len(els) == 0 {
-		return
-	}
-	if opts.Quiet {
-		slog.Debug("quiet flag is set, returning from print function")
-		return
-	}
-
-	if opts.Tree {
-		ftree := AddFilesToTree(els)
-		ftree.PrintTree("")
-		return
-	}
-
-	// I am unsure of how large this buffer should be. Testing or profiling might be necessary to
-	// find what is reasonable. The default buffer size was flushing automatically before being told to.
-	// This might be okay in itself, and we might not need to manually set a buffer ta all (or flush).
-
-	w := bufio.NewWriterSize(os.Stdout, 4096*bufLength)
-
-	for i, file := range els {
-		fp := filepath.ToSlash(file.Path)
-		if opts.Absolute {
-			fp, _ = filepath.Abs(file.Path)
-			fp = filepath.ToSlash(fp)
-		}
-		res := fp + "\n"
-
-		w.WriteString(res)
-		if i%bufLength == 0 {
-			w.Flush()
-		}
-	}
-
-	w.Flush()
-}
-
-// This file has largely been generated with GPT4.
-
-type TreeNode struct {
-	name     string
-	children map[string]*TreeNode
-}
-
-func NewTreeNode(name string) *TreeNode {
-	return &TreeNode{name: name, children: make(map[string]*TreeNode)}
-}
-
-func (t *TreeNode) AddPath(path string) {
-	parts := strings.Split(path, "/")
-	current := t
-	for _, part := range parts {
-		if _, exists := current.children[part]; !exists {
-			current.children[part] = NewTreeNode(part)
-		}
-		current = current.children[part]
-	}
-}
-
-func (t *TreeNode) PrintTree(prefix string) {
-	keys := make([]string, 0, len(t.children))
-	for k := range t.children {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys) // Sort keys to print in order
-
-	for i, key := range keys {
-		child := t.children[key]
-		if i == len(t.children)-1 {
-			fmt.Println(prefix + "└── " + child.name)
-			child.PrintTree(prefix + "    ")
-		} else {
-			fmt.Println(prefix + "├── " + child.name)
-			child.PrintTree(prefix + "│   ")
-		}
-	}
-}
-
-func AddFilesToTree(files []*Finfo) *TreeNode {
-	if len(files) == 0 {
-		return nil
-	}
-
-	commonRoot := strings.Split(filepath.ToSlash(files[0].Path), "/")[0]
-	root := NewTreeNode(commonRoot)
-
-	for _, file := range files {
-		trimmedPath := strings.TrimPrefix(filepath.ToSlash(file.Path), commonRoot+"/")
-		root.AddPath(trimmedPath)
-	}
-
-	return root
-}
+package list
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// the interval of flushing the buffer
+const bufLength = 500
+
+func PrintWithBuf(els []*Finfo, opts *Options) {
+	if opts.Count {
+		fmt.Println(len(els))
+		return
+	}
+
+	if len(els) == 0 && opts.Format != "json" {
+		return
+	}
+
+	if opts.Clipboard != "" {
+		if err := CopyResult(els, opts); err != nil {
+			slog.Error("error copying to clipboard", "error", err)
+		}
+	}
+
+	if opts.Quiet {
+		slog.Debug("quiet flag is set, returning from print function")
+		return
+	}
+
+	// I am unsure of how large this buffer should be. Testing or profiling might be necessary to
+	// find what is reasonable. The default buffer size was flushing automatically before being told to.
+	// This might be okay in itself, and we might not need to manually set a buffer ta all (or flush).
+
+	w := bufio.NewWriterSize(os.Stdout, 4096*bufLength)
+	WriteResult(w, els, opts)
+	w.Flush()
+}
+
+// WriteResult writes the files as they are printed, as a tree, long listing, format or paths.
+func WriteResult(w *bufio.Writer, els []*Finfo, opts *Options) {
+	if opts.Tree {
+		PrintTree(w, BuildTree(els, opts), opts)
+		return
+	}
+
+	if opts.Long {
+		PrintLong(w, els, opts)
+		return
+	}
+
+	if opts.Format != "" || opts.Printf != "" {
+		if err := PrintFormat(w, els, opts); err != nil {
+			slog.Error("error printing format", "error", err)
+		}
+		return
+	}
+
+	colors := NewColors(opts)
+	end := Terminator(opts)
+	for i, file := range els {
+		// groups are separated by an empty line, but not by an empty NUL terminated path
+		if i > 0 && file.Group != els[i-1].Group && !opts.Print0 {
+			w.WriteString(end)
+		}
+		fp := colors.Paint(FormatPath(file, opts), file)
+		res := fp + end
+		if file.Hash != "" {
+			res = file.Hash + "  " + res
+		}
+		if opts.DiskUsage {
+			res = FormatSize(file.DiskSize(opts), opts) + "\t" + res
+		}
+		if opts.LineNumber && len(file.Lines) > 0 {
+			res = ""
+			for _, n := range file.Lines {
+				res += fp + ":" + strconv.Itoa(n) + end
+			}
+		}
+
+		w.WriteString(res)
+		if i%bufLength == 0 {
+			w.Flush()
+		}
+	}
+}
+
+// FormatPath returns the path of the file as it is printed.
+func FormatPath(fi *Finfo, opts *Options) string {
+	if opts.Absolute {
+		fp, _ := filepath.Abs(fi.Path)
+		return filepath.ToSlash(fp)
+	}
+	return filepath.ToSlash(fi.Path)
+}
+
+// FormatSize returns the size as it is printed.
+func FormatSize(size int64, opts *Options) string {
+	if opts.Human {
+		return HumanSize(size)
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// Terminator returns the string printed after each element.
+func Terminator(opts *Options) string {
+	if opts.Print0 {
+		return "\x00"
+	}
+	return "\n"
+}
@@ -0,0 +1,35 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestWriteResultGroups(t *testing.T) {
+	files := []*Finfo{
+		{Path: "a", Group: 1},
+		{Path: "b", Group: 1},
+		{Path: "c", Group: 2},
+		{Path: "d", Group: 2},
+	}
+	tests := []struct {
+		print0 bool
+		want   string
+	}{
+		{false, "a\nb\n\nc\nd\n"},
+		{true, "a\x00b\x00c\x00d\x00"},
+	}
+	for _, tt := range tests {
+		opts := &Options{}
+		opts.Color = "never"
+		opts.Print0 = tt.print0
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		WriteResult(w, files, opts)
+		w.Flush()
+		if buf.String() != tt.want {
+			t.Errorf("print0=%v: got %q, want %q", tt.print0, buf.String(), tt.want)
+		}
+	}
+}
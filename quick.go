@@ -0,0 +1,131 @@
+package list
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	gf "github.com/jessevdk/go-flags"
+)
+
+// QuickCommands maps the letters usable after `?` to the flags they expand to, e.g., `?rz` is `-r -z`.
+// Keys may be longer than a single letter, in which case they act as macros; the longest key matching wins.
+var QuickCommands = map[string][]string{
+	"m": {"-i", Audio, "-i", Video, "-i", Image},
+	"a": {"-i", Audio},
+	"v": {"-i", Video},
+	"i": {"-i", Image},
+	"h": {"-h"},
+	"n": {"-S", "name"},
+	"c": {"-S", "creation"},
+	"t": {"-S", "time"},
+	"f": {"--files"},
+	"d": {"--dirs"},
+	"r": {"-r"},
+	"z": {"-z"},
+	"C": {"-C"},
+	"R": {"-a"},
+	"M": {"-m", "1000"},
+}
+
+// RegisterQuick defines the quick command key as the given flags, replacing any previous definition.
+func RegisterQuick(key string, flags ...string) {
+	QuickCommands[key] = flags
+}
+
+// QuickCommand returns the flags of every quick command in arg, which is given without the leading `?`.
+func QuickCommand(arg string) (flags []string) {
+	for len(arg) > 0 {
+		var key string
+		for k := range QuickCommands {
+			if len(k) > len(key) && strings.HasPrefix(arg, k) {
+				key = k
+			}
+		}
+
+		if key == "" {
+			slog.Debug("unknown quick command", "arg", arg)
+			_, size := utf8.DecodeRuneInString(arg)
+			arg = arg[size:]
+			continue
+		}
+
+		flags = append(flags, QuickCommands[key]...)
+		arg = arg[len(key):]
+	}
+	return
+}
+
+// flagParser looks up the flags of Options, see takesValue.
+var flagParser = sync.OnceValue(func() *gf.Parser { return gf.NewParser(&Options{}, gf.None) })
+
+// takesValue reports whether the argument after arg is the value of the flag arg ends with.
+func takesValue(arg string) bool {
+	var opt *gf.Option
+	switch {
+	case len(arg) < 2 || arg[0] != '-' || arg == "--":
+		return false
+	case strings.HasPrefix(arg, "--"):
+		if strings.Contains(arg, "=") {
+			return false
+		}
+		opt = flagParser().FindOptionByLongName(arg[2:])
+	default:
+		for i, r := range arg[1:] {
+			opt = flagParser().FindOptionByShortName(r)
+			if opt != nil && opt.Field().Type.Kind() != reflect.Bool && i+utf8.RuneLen(r) < len(arg)-1 {
+				return false // the value follows the letter
+			}
+		}
+	}
+	return opt != nil && opt.Field().Type.Kind() != reflect.Bool && !opt.OptionalArgument
+}
+
+// ExpandQuick replaces every `?` argument with the flags of its quick commands. Values of flags,
+// e.g., `-s ?r`, and arguments after `--` are left as they are. `??` prints the quick command table and exits.
+func ExpandQuick(args []string) []string {
+	res := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(res, args[i:]...)
+		case takesValue(arg) && i+1 < len(args):
+			res = append(res, arg, args[i+1])
+			i++
+		case arg == "??":
+			PrintQuickCommands(os.Stdout)
+			os.Exit(0)
+		case len(arg) > 1 && arg[0] == '?':
+			flags := QuickCommand(arg[1:])
+			slog.Debug("implicitly found cmd", "type", "QuickCommand", "arg", arg, "flags", flags)
+			res = append(res, flags...)
+		default:
+			res = append(res, arg)
+		}
+	}
+	return res
+}
+
+// PrintQuickCommands writes the active quick command table.
+func PrintQuickCommands(w io.Writer) {
+	keys := make([]string, 0, len(QuickCommands))
+	for k := range QuickCommands {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	width := 0
+	for _, k := range keys {
+		width = max(width, len(k))
+	}
+	for _, k := range keys {
+		fmt.Fprintf(w, "%-*s  %s\n", width, k, strings.Join(QuickCommands[k], " "))
+	}
+}
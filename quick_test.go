@@ -0,0 +1,53 @@
+package list
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandQuick(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"?rz"}, []string{"-r", "-z"}},
+		{[]string{"dir", "?f"}, []string{"dir", "--files"}},
+		{[]string{"-s", "?r"}, []string{"-s", "?r"}},
+		{[]string{"-I", "?z", "?r"}, []string{"-I", "?z", "-r"}},
+		{[]string{"-rs", "?r"}, []string{"-rs", "?r"}},
+		{[]string{"-sfoo", "?r"}, []string{"-sfoo", "-r"}},
+		{[]string{"--search", "?z"}, []string{"--search", "?z"}},
+		{[]string{"--search=x", "?z"}, []string{"--search=x", "-z"}},
+		{[]string{"--clipboard", "?z"}, []string{"--clipboard", "-z"}},
+		{[]string{"-r", "?z"}, []string{"-r", "-z"}},
+		{[]string{"--", "?z"}, []string{"--", "?z"}},
+		{[]string{"-s"}, []string{"-s"}},
+	}
+	for _, tt := range tests {
+		if got := ExpandQuick(tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestQuickCommand(t *testing.T) {
+	prev := QuickCommands
+	defer func() { QuickCommands = prev }()
+	QuickCommands = map[string][]string{"r": {"-r"}, "z": {"-z"}, "rz": {"-R"}}
+
+	tests := []struct {
+		arg  string
+		want []string
+	}{
+		{"r", []string{"-r"}},
+		{"rz", []string{"-R"}},
+		{"zr", []string{"-z", "-r"}},
+		{"rzr", []string{"-R", "-r"}},
+		{"xr", []string{"-r"}},
+	}
+	for _, tt := range tests {
+		if got := QuickCommand(tt.arg); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
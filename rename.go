@@ -0,0 +1,314 @@
+package list
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Editor returns the command line of the editor of the user, from $VISUAL or $EDITOR.
+func Editor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(env)); len(args) != 0 {
+			return args
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// EditRenames writes the paths of the files to a temporary file, opens it in the editor and renames
+// the files to the edited paths, like vidir. The renames are previewed and confirmed before they are applied.
+// Removing a line leaves its file as it is.
+func EditRenames(res *Result, opts *Options) int {
+	var files []*Finfo
+	var failed int
+	for _, fi := range res.Files {
+		switch {
+		case fi.Archive != "":
+			slog.Error("archive entries can not be renamed", "path", fi.Path)
+		case strings.ContainsAny(fi.Path, "\n\r"):
+			slog.Error("paths with newlines can not be edited", "path", fi.Path)
+		default:
+			files = append(files, fi)
+			continue
+		}
+		failed++
+	}
+	if len(files) == 0 {
+		return min(failed, 1)
+	}
+
+	tmp, err := os.CreateTemp("", "list-rename-*.txt")
+	if err != nil {
+		slog.Error("error creating file for editing", "error", err)
+		return 1
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for i, fi := range files {
+		fmt.Fprintf(w, "%d\t%s\n", i+1, fi.Path)
+	}
+	err = w.Flush()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		slog.Error("error writing file for editing", "error", err)
+		return 1
+	}
+
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			slog.Error("error editing names", "error", err)
+			return 1
+		}
+		ops, err := readRenames(tmp.Name(), files)
+		if err != nil {
+			// reopen the editor with the edits kept, like vidir
+			fmt.Fprintln(os.Stderr, "error:", err)
+			switch answer := Prompt("[e]dit, [q]uit: "); answer {
+			case "e", "edit":
+				continue
+			default:
+				return 1
+			}
+		}
+		if len(ops) == 0 {
+			fmt.Fprintln(os.Stderr, "nothing to rename")
+			return min(failed, 1)
+		}
+
+		ordered := OrderRenames(ops)
+		for _, op := range ordered {
+			fmt.Println(op.String())
+		}
+		if opts.DryRun {
+			return min(failed, 1)
+		}
+
+		switch answer := Prompt(fmt.Sprintf("Rename %d files? [y]es, [n]o, [e]dit: ", len(ops))); answer {
+		case "y", "yes":
+			return applyRenames(ops, ordered, failed, opts)
+		case "e", "edit":
+			continue
+		default:
+			return 1
+		}
+	}
+}
+
+// runEditor opens the file in the editor, waiting for it to exit.
+func runEditor(name string) error {
+	args := append(Editor(), name)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = terminal(), os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s: %w", QuoteCommand(args), err)
+	}
+	return nil
+}
+
+// readRenames returns the renames of the edited paths, or why they can not be applied.
+func readRenames(name string, files []*Finfo) ([]Op, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ops, err := ParseRenames(f, files)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckRenames(ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// ParseRenames reads the edited lines of `number<tab>path`, returning a rename for every path which changed.
+func ParseRenames(r io.Reader, files []*Finfo) (ops []Op, err error) {
+	seen := make([]bool, len(files))
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		num, path, ok := strings.Cut(text, "\t")
+		n, err := strconv.Atoi(strings.TrimSpace(num))
+		switch {
+		case !ok || err != nil:
+			return nil, fmt.Errorf("line %d: expected a number and a path separated by a tab", line)
+		case n < 1 || n > len(files):
+			return nil, fmt.Errorf("line %d: unknown number %d", line, n)
+		case seen[n-1]:
+			return nil, fmt.Errorf("line %d: number %d is used twice", line, n)
+		case path == "":
+			return nil, fmt.Errorf("line %d: empty path", line)
+		}
+		seen[n-1] = true
+
+		fi := files[n-1]
+		if filepath.Clean(path) != filepath.Clean(fi.Path) {
+			ops = append(ops, Op{Action: ActRename, From: fi.Path, To: path, fi: fi})
+		}
+	}
+	return ops, scanner.Err()
+}
+
+// CheckRenames refuses renames to the same path, and to existing files which are not renamed themselves.
+func CheckRenames(ops []Op) error {
+	from := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		from[filepath.Clean(op.From)] = true
+	}
+
+	to := make(map[string]string, len(ops))
+	for _, op := range ops {
+		dst := filepath.Clean(op.To)
+		if prev, ok := to[dst]; ok {
+			return fmt.Errorf("%q and %q would both be renamed to %q", prev, op.From, op.To)
+		}
+		to[dst] = op.From
+		if exists(dst) && !from[dst] && !sameFile(op.From, dst) {
+			return fmt.Errorf("can not rename %q to %q: destination exists", op.From, op.To)
+		}
+	}
+	return nil
+}
+
+// OrderRenames orders the renames so that every destination is free when it is renamed to.
+// Files are renamed before the directories they are in. Swaps and longer cycles are broken
+// by first renaming one of the files to a temporary name.
+func OrderRenames(ops []Op) (ordered []Op) {
+	pending := append([]Op(nil), ops...)
+	sort.SliceStable(pending, func(i, j int) bool {
+		return strings.Count(filepath.Clean(pending[i].From), string(filepath.Separator)) >
+			strings.Count(filepath.Clean(pending[j].From), string(filepath.Separator))
+	})
+
+	// from counts the pending renames of each path, and inside those of the paths beneath each directory
+	from := make(map[string]int, len(pending))
+	inside := map[string]int{}
+	parents := func(path string, n int) {
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			inside[dir] += n
+			if dir == filepath.Dir(dir) {
+				return
+			}
+		}
+	}
+	for _, op := range pending {
+		from[filepath.Clean(op.From)]++
+		parents(filepath.Clean(op.From), 1)
+	}
+
+	for len(pending) > 0 {
+		var rest []Op
+		for _, op := range pending {
+			src := filepath.Clean(op.From)
+			if from[filepath.Clean(op.To)] > 0 || inside[src] > 0 {
+				rest = append(rest, op)
+				continue
+			}
+			ordered = append(ordered, op)
+			from[src]--
+			parents(src, -1)
+		}
+
+		if len(rest) == len(pending) {
+			// every destination is taken by another rename, move one with nothing beneath it out of the way.
+			// The temporary name is in the same directory, so the renames beneath each directory stay the same.
+			i := slices.IndexFunc(rest, func(op Op) bool { return inside[filepath.Clean(op.From)] == 0 })
+			op := &rest[max(i, 0)]
+			tmp := filepath.Join(filepath.Dir(op.From), ".list-rename-"+filepath.Base(op.From))
+			if exists(tmp) {
+				tmp = freeName(tmp, exists)
+			}
+			ordered = append(ordered, Op{Action: ActRename, From: op.From, To: tmp, fi: op.fi})
+			from[filepath.Clean(op.From)]--
+			op.From = tmp
+		}
+		pending = rest
+	}
+	return
+}
+
+// applyRenames runs the ordered renames, counting the renamed files by the renames to their edited paths.
+func applyRenames(ops, ordered []Op, failed int, opts *Options) int {
+	edited := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		edited[op.To] = true
+	}
+
+	var renamed int
+	done := ApplyOps(ordered, opts)
+	for _, op := range done {
+		if edited[op.To] {
+			renamed++
+		}
+	}
+	failed += len(ops) - renamed
+
+	if !opts.Quiet {
+		fmt.Fprintf(os.Stderr, "%d %s, %d failed\n", renamed, actionDone[ActRename], failed)
+	}
+	return min(failed, 1)
+}
+
+func sameFile(a, b string) bool {
+	ai, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Lstat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// terminal returns the terminal of the user for reading, as stdin may be used for piped paths.
+var terminal = sync.OnceValue(func() *os.File {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return os.Stdin
+	}
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	if tty, err := os.Open(name); err == nil {
+		return tty
+	}
+	return os.Stdin
+})
+
+// prompter reads the answers of every prompt, so input buffered by one is not lost to the next.
+var prompter = sync.OnceValue(func() *bufio.Reader { return bufio.NewReader(terminal()) })
+
+// Prompt asks the user a question on stderr, returning the lowercased answer read from the terminal.
+func Prompt(question string) string {
+	fmt.Fprint(os.Stderr, question)
+	answer, err := prompter().ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(answer))
+}
@@ -0,0 +1,121 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRenames(t *testing.T) {
+	files := []*Finfo{{Path: "a.txt"}, {Path: "dir/b.txt"}, {Path: "c.txt"}}
+	tests := []struct {
+		name  string
+		input string
+		want  []string // from>to
+		err   string
+	}{
+		{"unchanged", "1\ta.txt\n2\tdir/b.txt\n3\tc.txt\n", nil, ""},
+		{"renamed", "1\tx.txt\n2\tdir/./b.txt\n3\tdir/c.txt\n", []string{"a.txt>x.txt", "c.txt>dir/c.txt"}, ""},
+		{"removed lines and blanks", "\n2\tb.txt\r\n\n", []string{"dir/b.txt>b.txt"}, ""},
+		{"no tab", "1 a.txt\n", nil, "line 1: expected"},
+		{"not a number", "x\ta.txt\n", nil, "line 1: expected"},
+		{"unknown number", "1\ta.txt\n4\td.txt\n", nil, "line 2: unknown number 4"},
+		{"duplicate number", "1\ta.txt\n1\tb.txt\n", nil, "line 2: number 1 is used twice"},
+		{"empty path", "3\t\n", nil, "line 1: empty path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := ParseRenames(strings.NewReader(tt.input), files)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, op := range ops {
+				got = append(got, op.From+">"+op.To)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderRenames(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		renames [][2]string
+		err     string
+	}{
+		{"chain", []string{"a", "b"}, [][2]string{{"a", "b"}, {"b", "c"}}, ""},
+		{"swap", []string{"a", "b"}, [][2]string{{"a", "b"}, {"b", "a"}}, ""},
+		{"cycle", []string{"a", "b", "c"}, [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}}, ""},
+		{"swap taking the temporary name", []string{"a", "b", ".list-rename-a"}, [][2]string{{"a", "b"}, {"b", "a"}}, ""},
+		{"file before its directory", []string{"d/f"}, [][2]string{{"d", "e"}, {"d/f", "d/g"}}, ""},
+		{"swap inside a renamed directory", []string{"d/a", "d/b"}, [][2]string{{"d", "e"}, {"d/a", "d/b"}, {"d/b", "d/a"}}, ""},
+		{"cycle inside renamed directories", []string{"d/s/a", "d/s/b", "d/s/c"},
+			[][2]string{{"d/s", "d/t"}, {"d", "e"}, {"d/s/a", "d/s/b"}, {"d/s/b", "d/s/c"}, {"d/s/c", "d/s/a"}}, ""},
+		{"existing file", []string{"a", "x"}, [][2]string{{"a", "x"}}, "destination exists"},
+		{"same destination", []string{"a", "b"}, [][2]string{{"a", "c"}, {"b", "c"}}, "would both be renamed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				writeFiles(t, dir, map[string]string{name: name})
+			}
+			var ops []Op
+			for _, r := range tt.renames {
+				ops = append(ops, Op{Action: ActRename, From: filepath.Join(dir, r[0]), To: filepath.Join(dir, r[1])})
+			}
+
+			err := CheckRenames(ops)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, op := range OrderRenames(ops) {
+				if exists(op.To) {
+					t.Fatalf("%s is renamed to %s, which exists", op.From, op.To)
+				}
+				if err := os.Rename(op.From, op.To); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// every file ends up at its edited path, or under the edited paths of its directories, listed deepest first
+			for _, name := range tt.files {
+				dst := name
+				for _, r := range tt.renames {
+					if dst == r[0] {
+						dst = r[1]
+						break
+					}
+				}
+				for _, r := range tt.renames {
+					if rel, ok := strings.CutPrefix(dst, r[0]+"/"); ok {
+						dst = r[1] + "/" + rel
+					}
+				}
+				if got := readFile(t, filepath.Join(dir, dst)); got != name {
+					t.Errorf("%s has %q, want %q", dst, got, name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package list
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// patternFields are the fields of rename patterns which do not come from --rename-regex.
+var patternFields = map[string]bool{
+	"n": true, "dn": true, "name": true, "stem": true, "ext": true, "parent": true, "date": true, "exif": true,
+}
+
+// patternTransforms change the case of a field, e.g., {stem|lower}.
+var patternTransforms = map[string]func(string) string{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"title": titleCase,
+}
+
+// RenamePattern is a parsed --rename-pattern, e.g., `{parent}_{n:03}{ext}`.
+type RenamePattern struct {
+	segs []patternSeg
+	re   *regexp.Regexp
+}
+
+// patternSeg is either literal text or a field with its argument and case transforms.
+type patternSeg struct {
+	lit        string
+	field, arg string
+	transforms []func(string) string
+}
+
+// ParseRenamePattern parses the pattern. Fields are written as {field}, {field:arg} and {field|transform},
+// and the capture groups of regex as {1} or by their name. Literal braces are written as {{ and }}.
+func ParseRenamePattern(pattern, regex string) (*RenamePattern, error) {
+	p := &RenamePattern{}
+	if regex != "" {
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --rename-regex: %w", err)
+		}
+		p.re = re
+	}
+
+	var lit strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(pattern) && pattern[i+1] == c:
+			lit.WriteByte(c)
+			i++
+			continue
+		case c == '}':
+			return nil, fmt.Errorf("unmatched } at %d, write }} for a literal brace", i)
+		case c != '{':
+			lit.WriteByte(c)
+			continue
+		}
+
+		end := strings.IndexByte(pattern[i:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("unclosed { at %d", i)
+		}
+		seg, err := p.parseField(pattern[i+1 : i+end])
+		if err != nil {
+			return nil, err
+		}
+		if lit.Len() > 0 {
+			p.segs = append(p.segs, patternSeg{lit: lit.String()})
+			lit.Reset()
+		}
+		p.segs = append(p.segs, seg)
+		i += end
+	}
+	if lit.Len() > 0 {
+		p.segs = append(p.segs, patternSeg{lit: lit.String()})
+	}
+	return p, nil
+}
+
+func (p *RenamePattern) parseField(s string) (seg patternSeg, err error) {
+	parts := strings.Split(s, "|")
+	seg.field, seg.arg, _ = strings.Cut(parts[0], ":")
+	for _, name := range parts[1:] {
+		fn, ok := patternTransforms[name]
+		if !ok {
+			return seg, fmt.Errorf("unknown transform %q in {%s}, expected upper, lower or title", name, s)
+		}
+		seg.transforms = append(seg.transforms, fn)
+	}
+
+	switch {
+	case patternFields[seg.field]:
+	case p.re == nil:
+		return seg, fmt.Errorf("unknown field {%s}", s)
+	case isDigits(seg.field):
+		if n, _ := strconv.Atoi(seg.field); n > p.re.NumSubexp() {
+			return seg, fmt.Errorf("--rename-regex has no group %s", seg.field)
+		}
+	case p.re.SubexpIndex(seg.field) == -1:
+		return seg, fmt.Errorf("unknown field {%s}, not a group of --rename-regex", s)
+	}
+
+	if (seg.field == "n" || seg.field == "dn") && seg.arg != "" && !isDigits(seg.arg) {
+		return seg, fmt.Errorf("invalid width in {%s}, e.g., {%s:03}", s, seg.field)
+	}
+	return seg, nil
+}
+
+// patternFile is the file a pattern is rendered for, with its counters.
+type patternFile struct {
+	fi    *Finfo
+	n, dn int
+}
+
+// Render returns the new name of the file, or false if it does not match --rename-regex.
+func (p *RenamePattern) Render(f patternFile) (string, bool) {
+	var groups []string
+	if p.re != nil {
+		if groups = p.re.FindStringSubmatch(f.fi.Name); groups == nil {
+			return "", false
+		}
+	}
+
+	var sb strings.Builder
+	for _, seg := range p.segs {
+		if seg.field == "" {
+			sb.WriteString(seg.lit)
+			continue
+		}
+		s := p.field(seg, f, groups)
+		for _, fn := range seg.transforms {
+			s = fn(s)
+		}
+		sb.WriteString(s)
+	}
+	return sb.String(), true
+}
+
+func (p *RenamePattern) field(seg patternSeg, f patternFile, groups []string) string {
+	fi := f.fi
+	ext := filepath.Ext(fi.Name)
+	if fi.IsDir {
+		ext = ""
+	}
+
+	switch seg.field {
+	case "n", "dn":
+		n := f.n
+		if seg.field == "dn" {
+			n = f.dn
+		}
+		width, _ := strconv.Atoi(seg.arg)
+		return fmt.Sprintf("%0*d", width, n)
+	case "name":
+		return fi.Name
+	case "stem":
+		return strings.TrimSuffix(fi.Name, ext)
+	case "ext":
+		return ext
+	case "parent":
+		dir := filepath.Dir(filepath.Clean(fi.Path))
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		return filepath.Base(dir)
+	case "date", "exif":
+		t := fi.ModTime
+		if t.IsZero() {
+			if info, err := os.Stat(fi.Path); err == nil {
+				t = info.ModTime()
+			}
+		}
+		if seg.field == "exif" {
+			if et, ok := ExifTime(fi); ok {
+				t = et
+			}
+		}
+		layout := seg.arg
+		if layout == "" {
+			layout = time.DateOnly
+		}
+		return t.Format(layout)
+	}
+
+	if isDigits(seg.field) {
+		n, _ := strconv.Atoi(seg.field)
+		return groups[n]
+	}
+	return groups[p.re.SubexpIndex(seg.field)]
+}
+
+// PatternRenames returns the renames of the files to the pattern, numbered in the order of the result.
+// The new names are relative to the directory of each file. Files which do not match --rename-regex are skipped.
+func PatternRenames(res *Result, opts *Options) (ops []Op, skipped, failed int, err error) {
+	p, err := ParseRenamePattern(opts.RenamePattern, opts.RenameRegex)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	n := opts.RenameStart
+	dirs := map[string]int{}
+	for _, fi := range res.Files {
+		if fi.Archive != "" {
+			slog.Error("archive entries can not be renamed", "path", fi.Path)
+			failed++
+			continue
+		}
+
+		dir := filepath.Dir(filepath.Clean(fi.Path))
+		name, ok := p.Render(patternFile{fi: fi, n: n, dn: opts.RenameStart + dirs[dir]})
+		if !ok {
+			skipped++
+			continue
+		}
+		n++
+		dirs[dir]++
+
+		if name == "" || strings.ContainsAny(name, "\x00\n") {
+			slog.Error("invalid name from pattern", "path", fi.Path, "name", name)
+			failed++
+			continue
+		}
+		to := filepath.Join(dir, filepath.FromSlash(name))
+		if to != filepath.Clean(fi.Path) {
+			ops = append(ops, Op{Action: ActRename, From: fi.Path, To: to, fi: fi})
+		}
+	}
+
+	if err := CheckRenames(ops); err != nil {
+		return nil, skipped, failed, err
+	}
+	return
+}
+
+// RenameByPattern renames the files with --rename-pattern, or prints the renames with --dry-run.
+// No file is renamed if any of the new names collide.
+func RenameByPattern(res *Result, opts *Options) int {
+	ops, skipped, failed, err := PatternRenames(res, opts)
+	if err != nil {
+		slog.Error("error renaming", "error", err)
+		return 1
+	}
+
+	ordered := OrderRenames(ops)
+	if opts.DryRun {
+		for _, op := range ordered {
+			fmt.Println(op.String())
+		}
+		return min(failed, 1)
+	}
+
+	if skipped > 0 && !opts.Quiet {
+		fmt.Fprintf(os.Stderr, "%d files did not match --rename-regex\n", skipped)
+	}
+	return applyRenames(ops, ordered, failed, opts)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// titleCase upper cases the first letter of every word.
+func titleCase(s string) string {
+	rs := []rune(s)
+	start := true
+	for i, r := range rs {
+		if start && unicode.IsLetter(r) {
+			rs[i] = unicode.ToUpper(r)
+		} else {
+			rs[i] = unicode.ToLower(r)
+		}
+		start = !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+	return string(rs)
+}
@@ -0,0 +1,118 @@
+package list
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenamePattern(t *testing.T) {
+	mod := time.Date(2024, 3, 9, 14, 5, 6, 0, time.UTC)
+	tests := []struct {
+		pattern, regex string
+		name           string
+		want           string // the rendered name, "-" if the file does not match
+		err            string
+	}{
+		{"{n}{ext}", "", "a.JPG", "7.JPG", ""},
+		{"{n:03}_{dn:2}", "", "a.jpg", "007_02", ""},
+		{"{stem|upper}-{name}", "", "a.b.txt", "A.B-a.b.txt", ""},
+		{"{stem|title}{ext|lower}", "", "hello wORLD-2x.TXT", "Hello World-2x.txt", ""},
+		{"{date}_{date:150405}", "", "a", "2024-03-09_140506", ""},
+		{"{{{n}}}", "", "a", "{7}", ""},
+		{"{1}-{num}{ext}", `IMG_(\d+)_(?P<num>\d+)`, "IMG_12_34.png", "12-34.png", ""},
+		{"{1}", `IMG_(\d+)`, "DSC_1.png", "-", ""},
+		{"{size}", "", "a", "", "unknown field {size}"},
+		{"{n|caps}", "", "a", "", `unknown transform "caps"`},
+		{"{n:x}", "", "a", "", "invalid width"},
+		{"{2}", `(\d+)`, "a", "", "no group 2"},
+		{"{num}", `(\d+)`, "a", "", "not a group"},
+		{"{n", "", "a", "", "unclosed {"},
+		{"n}", "", "a", "", "unmatched }"},
+		{"{n}", "(", "a", "", "invalid --rename-regex"},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseRenamePattern(tt.pattern, tt.regex)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: got error %v, want %q", tt.pattern, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.pattern, err)
+			continue
+		}
+		got, ok := p.Render(patternFile{fi: &Finfo{Name: tt.name, Path: tt.name, ModTime: mod}, n: 7, dn: 2})
+		if !ok {
+			got = "-"
+		}
+		if got != tt.want {
+			t.Errorf("%q on %q: got %q, want %q", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPatternRenames(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a/x.jpg": "", "a/y.jpg": "", "b/z.jpg": "", "b/skip.png": ""})
+	files := func(names ...string) *Result {
+		res := &Result{}
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			res.Files = append(res.Files, &Finfo{Name: filepath.Base(path), Path: path})
+		}
+		return res
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		regex   string
+		files   []string
+		want    []string // renames relative to dir
+		skipped int
+		err     string
+	}{
+		{"counters", "{n}_{dn}{ext}", "", []string{"a/x.jpg", "b/z.jpg", "a/y.jpg"},
+			[]string{"a/x.jpg>a/1_1.jpg", "b/z.jpg>b/2_1.jpg", "a/y.jpg>a/3_2.jpg"}, 0, ""},
+		{"unchanged", "{name}", "", []string{"a/x.jpg"}, nil, 0, ""},
+		{"regex", "{n}{ext}", `\.jpg$`, []string{"b/skip.png", "b/z.jpg"}, []string{"b/z.jpg>b/1.jpg"}, 1, ""},
+		{"subdirectory", "sorted/{name}", "", []string{"a/x.jpg"}, []string{"a/x.jpg>a/sorted/x.jpg"}, 0, ""},
+		{"without extension", "{n}", "", []string{"a/x.jpg", "a/y.jpg"}, []string{"a/x.jpg>a/1", "a/y.jpg>a/2"}, 0, ""},
+		{"same name", "same", "", []string{"a/x.jpg", "a/y.jpg"}, nil, 0, "would both be renamed"},
+		{"existing file", "y.jpg", "", []string{"a/x.jpg"}, nil, 0, "destination exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{}
+			opts.RenamePattern, opts.RenameRegex, opts.RenameStart = tt.pattern, tt.regex, 1
+			ops, skipped, failed, err := PatternRenames(files(tt.files...), opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || failed != 0 {
+				t.Fatalf("got error %v and %d failed", err, failed)
+			}
+			if skipped != tt.skipped {
+				t.Errorf("skipped %d, want %d", skipped, tt.skipped)
+			}
+
+			var got []string
+			for _, op := range ops {
+				from, _ := filepath.Rel(dir, op.From)
+				to, _ := filepath.Rel(dir, op.To)
+				got = append(got, filepath.ToSlash(from)+">"+filepath.ToSlash(to))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
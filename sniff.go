@@ -0,0 +1,131 @@
+package list
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// headLen is the amount of bytes read from the start of a file when sniffing its type.
+const headLen = 64
+
+// Signature describes the magic bytes of a file type, found at Offset.
+// Weak signatures are shared by container formats (e.g. docx and epub are zip files),
+// and are only used when the extension does not already classify the file.
+// Signatures too short to be told apart from other data only match files with the extension Ext.
+type Signature struct {
+	Offset int
+	Magic  []byte
+	Mask   Mask
+	Weak   bool
+	Ext    string
+}
+
+// Signatures are checked in order, the first match wins. Signatures of registered kinds are checked first.
+var Signatures = []Signature{
+	{0, []byte("\x89PNG\r\n\x1a\n"), MaskImage, false, ""},
+	{0, []byte("\xff\xd8\xff"), MaskImage, false, ""},
+	{0, []byte("GIF87a"), MaskImage, false, ""},
+	{0, []byte("GIF89a"), MaskImage, false, ""},
+	{8, []byte("WEBP"), MaskImage, false, ""},
+	{4, []byte("ftypavif"), MaskImage, false, ""},
+	{4, []byte("ftypheic"), MaskImage, false, ""},
+	{0, []byte("\x00\x00\x00\x0cJXL \r\n\x87\n"), MaskImage, false, ""},
+	{0, []byte("\xff\x0a"), MaskImage, false, ".jxl"},
+	{4, []byte("ftypM4A"), MaskAudio, false, ""},
+	{4, []byte("ftyp"), MaskVideo, false, ""},
+	{0, []byte("\x1a\x45\xdf\xa3"), MaskVideo, false, ""},
+	{8, []byte("AVI "), MaskVideo, false, ""},
+	{8, []byte("WAVE"), MaskAudio, false, ""},
+	{0, []byte("OggS"), MaskAudio, false, ""},
+	{0, []byte("fLaC"), MaskAudio, false, ""},
+	{0, []byte("ID3"), MaskAudio, false, ""},
+	{0, []byte("%PDF-"), MaskDocs, false, ""},
+	{0, []byte("PK\x03\x04"), MaskZipLike.Or(MaskArchive), true, ""},
+	{0, []byte("Rar!\x1a\x07"), MaskArchive, true, ""},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), MaskArchive, true, ""},
+	{0, []byte("\x1f\x8b"), MaskArchive, true, ""},
+	{0, []byte("\xfd7zXZ\x00"), MaskArchive, true, ""},
+	{0, []byte("\x28\xb5\x2f\xfd"), MaskArchive, true, ""},
+	{0, []byte("BZh"), MaskArchive, true, ""},
+}
+
+// ExtMask returns the mask of the file based on its extension, ignoring case.
+func ExtMask(name string) Mask {
+	return CntMap[strings.ToLower(filepath.Ext(name))]
+}
+
+// SniffHead returns the mask of the first signature found in head, the start of the file with the name.
+func SniffHead(head []byte, name string) (mask Mask, weak bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, k := range Kinds {
+		if mask, weak = matchSignatures(head, ext, k.sigs); mask != nil {
+			return
+		}
+	}
+	return matchSignatures(head, ext, Signatures)
+}
+
+func matchSignatures(head []byte, ext string, sigs []Signature) (Mask, bool) {
+	for _, sig := range sigs {
+		end := sig.Offset + len(sig.Magic)
+		if end > len(head) || (sig.Ext != "" && sig.Ext != ext) {
+			continue
+		}
+		if bytes.Equal(head[sig.Offset:end], sig.Magic) {
+			return sig.Mask, sig.Weak
+		}
+	}
+	return nil, false
+}
+
+// SniffMask reads the start of the file and returns its mask as determined by its content.
+// The extension based mask is returned when the content is not recognized.
+func SniffMask(fi *Finfo) Mask {
+	rc, err := fi.Open()
+	if err != nil {
+		slog.Debug("error opening file for sniffing", "path", fi.Path, "error", err)
+		return fi.Mask
+	}
+	defer rc.Close()
+
+	head := make([]byte, headLen)
+	n, _ := io.ReadFull(rc, head)
+
+	mask, weak := SniffHead(head[:n], fi.Name)
+	switch {
+	case mask.IsZero():
+		return fi.Mask
+	case weak && !fi.Mask.IsZero():
+		return fi.Mask
+	default:
+		return mask
+	}
+}
+
+// zipMagic starts every zip archive which is not empty.
+var zipMagic = []byte("PK\x03\x04")
+
+// IsZip reports whether the file is traversed as a zip archive with -z: files with the .zip extension,
+// and with --sniff any file which starts like a zip archive, unless its extension says it is a document.
+func IsZip(path string, opts *Options) bool {
+	mask := ExtMask(path)
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return true
+	}
+	if !opts.Sniff || !(mask.IsZero() || mask.Has(MaskZipLike)) {
+		return false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(zipMagic))
+	_, err = io.ReadFull(f, head)
+	return err == nil && bytes.Equal(head, zipMagic)
+}
@@ -0,0 +1,63 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSniffHead(t *testing.T) {
+	tests := []struct {
+		head string
+		name string
+		want Mask
+		weak bool
+	}{
+		{"\x89PNG\r\n\x1a\n....", "x", MaskImage, false},
+		{"\x00\x00\x00\x0cJXL \r\n\x87\n", "x", MaskImage, false},
+		{"\xff\x0a\x00\x01", "photo.jxl", MaskImage, false},
+		{"\xff\x0a\x00\x01", "photo.JXL", MaskImage, false},
+		{"\xff\x0a\x00\x01", "data.bin", nil, false},
+		{"PK\x03\x04rest", "x", MaskZipLike.Or(MaskArchive), true},
+		{"plain text", "x.txt", nil, false},
+	}
+	for _, tt := range tests {
+		mask, weak := SniffHead([]byte(tt.head), tt.name)
+		if !sameMask(mask, tt.want) || weak != tt.weak {
+			t.Errorf("%q %s: got %v %v, want %v %v", tt.head, tt.name, mask, weak, tt.want, tt.weak)
+		}
+	}
+}
+
+func sameMask(a, b Mask) bool {
+	return (a.IsZero() && b.IsZero()) || (a.Has(b) && b.Has(a))
+}
+
+func TestIsZip(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "noext"), map[string]string{"a": "a"}, 0)
+	writeZip(t, filepath.Join(dir, "doc.docx"), map[string]string{"a": "a"}, 0)
+	if err := os.WriteFile(filepath.Join(dir, "text"), []byte("PK but not"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		sniff bool
+		want  bool
+	}{
+		{"a.zip", false, true},
+		{"A.ZIP", false, true},
+		{"noext", false, false},
+		{"noext", true, true},
+		{"doc.docx", true, false},
+		{"text", true, false},
+	}
+	for _, tt := range tests {
+		opts := &Options{}
+		opts.Sniff = tt.sniff
+		if got := IsZip(filepath.Join(dir, tt.name), opts); got != tt.want {
+			t.Errorf("%s sniff=%v: got %v, want %v", tt.name, tt.sniff, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package list
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// escapes are the backslash escapes understood in templates, as shells pass them verbatim.
+var escapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\r`, "\r", `\0`, "\x00")
+
+// TemplateFuncs are the helpers available in --format and --printf templates.
+var TemplateFuncs = template.FuncMap{
+	"human": HumanSize,
+	"date":  func(layout string, t time.Time) string { return t.Format(layout) },
+	"ago":   Ago,
+	"base":  filepath.Base,
+	"dir":   func(path string) string { return filepath.ToSlash(filepath.Dir(path)) },
+	"ext":   filepath.Ext,
+	"stem":  func(path string) string { return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) },
+	"slash": filepath.ToSlash,
+	"abs": func(path string) string {
+		abs, _ := filepath.Abs(path)
+		return filepath.ToSlash(abs)
+	},
+	"kinds": func(m Mask) string { return strings.Join(KindNames(m), ",") },
+	"quote": ShellQuote,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseTemplate parses a template over Finfo, replacing backslash escapes such as \t and \n.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("format").Funcs(TemplateFuncs).Parse(escapes.Replace(text))
+}
+
+// Ago formats the time relative to now, e.g., 3h ago.
+func Ago(t time.Time) string {
+	d := time.Since(t)
+	suffix := " ago"
+	if d < 0 {
+		d, suffix = -d, " from now"
+	}
+
+	switch {
+	case d < time.Minute:
+		return strconv.Itoa(int(d.Seconds())) + "s" + suffix
+	case d < time.Hour:
+		return strconv.Itoa(int(d.Minutes())) + "m" + suffix
+	case d < 24*time.Hour:
+		return strconv.Itoa(int(d.Hours())) + "h" + suffix
+	case d < 365*24*time.Hour:
+		return strconv.Itoa(int(d.Hours()/24)) + "d" + suffix
+	default:
+		return strconv.Itoa(int(d.Hours()/24/365)) + "y" + suffix
+	}
+}
+
+// ShellQuote quotes the string for POSIX shells if it contains any special characters.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./,:+=@%", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -1,251 +1,391 @@
-package list
-
-import (
-	"archive/zip"
-	"io/fs"
-	"log"
-	"log/slog"
-	"os"
-	"path/filepath"
-	"strings"
-)
-
-type Result struct{ Files []*Finfo }
-
-func (r Result) Sar() []string {
-	res := make([]string, 0, len(r.Files))
-	for _, v := range r.Files {
-		res = append(res, v.Path)
-	}
-	return res
-}
-
-type Finfo struct {
-	Name      string
-	Path      string // includes name, relative path to cwd
-	Vany      int64  // any numeric value, used for sorting
-	Mask      uint32 // file kind, bitmask, see Mask* constants
-	IsDir     bool
-	IsArchive bool // is a readable archive; ziplike
-}
-
-type ResultFilters func(*Finfo)
-
-type FinfoParser func(string, fs.FileInfo) *Finfo
-
-func InitFileParser(opts *Options) FinfoParser {
-	return func(path string, info fs.FileInfo) *Finfo {
-		fi := &Finfo{
-			Name:  info.Name(),
-			Path:  path,
-			IsDir: info.IsDir(),
-		}
-
-		fi.Mask |= CntMap[filepath.Ext(fi.Name)]
-
-		if fi.Mask&MaskZipLike != 0 {
-			fi.IsArchive = true
-		}
-
-		switch StrToSortBy(opts.Sort) {
-		case ByMod:
-			addModT(fi, info)
-		case BySize:
-			addSize(fi, info)
-		case ByCreation:
-			addCreationT(fi, info)
-		}
-
-		return fi
-	}
-}
-func addModT(fi *Finfo, info fs.FileInfo) { fi.Vany = info.ModTime().Unix() }
-func addSize(fi *Finfo, info fs.FileInfo) { fi.Vany = info.Size() }
-
-type Traverser func(*Options, ResultFilters)
-
-func GetTraverser(opts *Options) Traverser {
-	switch {
-	case opts.ArgMode:
-		return TraverseArgs
-	case opts.FileMode != "":
-		return FileTraverser
-	default:
-		return TraverseFS
-	}
-}
-
-func FileTraverser(opts *Options, rfn ResultFilters) {
-	for _, arg := range opts.Args {
-		b, err := os.ReadFile(arg)
-		if err != nil {
-			slog.Error("error reading file", "arg", arg, "error", err)
-			continue
-		}
-
-		var res []string
-
-		switch opts.FileMode {
-		case "words", "w":
-			res = strings.Fields(string(b))
-		case "lines", "l":
-			res = strings.Split(string(b), "\n")
-		default:
-			res = strings.Split(string(b), "\n")
-		}
-
-		for _, line := range res {
-			rfn(StringParser(line))
-		}
-	}
-}
-
-func TraverseArgs(opts *Options, rfn ResultFilters) {
-	for _, arg := range opts.Args {
-		rfn(StringParser(arg))
-	}
-}
-
-func StringParser(s string) *Finfo {
-	return &Finfo{
-		Name: s,
-		Path: s,
-		Mask: CntMap[filepath.Ext(s)], // attempt, not guaranteed to be filepath
-	}
-}
-
-// TraverseFS traverses directories non-recursively and breadth first.
-func TraverseFS(opts *Options, rfn ResultFilters) {
-	var searchFn = func(string) bool { return true }
-	if len(opts.DirSearch) != 0 {
-		searchFn = func(str string) bool {
-			for _, k := range opts.DirSearch {
-				if strings.Contains(str, k) {
-					return true
-				}
-			}
-			return false
-		}
-	}
-	opts.DirSearch = append(opts.DirSearch, "./")
-
-	parser := InitFileParser(opts)
-
-	dirs := opts.Args
-
-	if len(dirs) == 0 {
-		dirs = append(dirs, "./")
-	}
-
-	var depth int
-	for len(dirs) != 0 {
-		if depth > opts.ToDepth {
-			return
-		}
-		var nd []string
-		for _, d := range dirs {
-			ext := filepath.Ext(d)
-			slog.Debug("traversing", "dir", d, "depth", depth, "ext", ext, "isarchive", CntMap[ext]&MaskZipLike != 0)
-
-			var files []fs.FileInfo
-
-			switch {
-			case opts.Archive && CntMap[ext]&MaskZipLike != 0 && searchFn(d):
-				files = TraverseZip(d, depth, opts)
-			default:
-				files = TraverseDir(d, depth, opts)
-			}
-
-			for i, info := range files {
-				if i > opts.MaxLimit {
-					break
-				}
-				name := info.Name()
-				path := filepath.Join(d, name)
-				if !opts.NoHide {
-					if _, ok := Hide[name]; ok || name[0] == '.' {
-						continue
-					}
-				}
-
-				if info.IsDir() && searchFn(name) {
-					nd = append(nd, path)
-				}
-
-				if opts.Archive && filepath.Ext(path) == ".zip" {
-					nd = append(nd, path)
-					continue
-				}
-
-				if depth < opts.FromDepth {
-					continue
-				}
-
-				rfn(parser(path, info))
-			}
-		}
-
-		dirs = nd
-		depth++
-	}
-}
-
-func TraverseDir(path string, depth int, opts *Options) (files []fs.FileInfo) {
-	entries, err := os.ReadDir(path)
-	if err != nil {
-		log.Fatalln(err)
-	}
-	for _, entry := range entries {
-		info, err := entry.Info()
-		if err != nil {
-			slog.Error("error reading file info", "file", entry.Name(), "error", err)
-			continue
-		}
-		switch {
-		case opts.DirOnly && info.IsDir():
-			files = append(files, info)
-		case opts.FileOnly && !info.IsDir():
-			files = append(files, info)
-		default:
-			files = append(files, info)
-		}
-
-	}
-	return
-}
-
-func TraverseZip(path string, depth int, opts *Options) (files []fs.FileInfo) {
-	r, err := zip.OpenReader(path)
-	if err != nil {
-		log.Fatalln(err)
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		fn := filepath.ToSlash(f.Name)
-
-		fdepth := depth + strings.Count(fn, "/")
-		if fdepth < opts.FromDepth || fdepth > opts.ToDepth {
-			continue
-		}
-		info := f.FileInfo()
-		if info.IsDir() {
-			continue
-		}
-
-		files = append(files, info)
-	}
-
-	return
-}
-
-func InitFilters(fns []Filter, res *Result) ResultFilters {
-	return func(fi *Finfo) {
-		for _, fn := range fns {
-			if !fn(fi) {
-				return
-			}
-		}
-		res.Files = append(res.Files, fi)
-	}
-}
+package list
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Result struct{ Files []*Finfo }
+
+func (r Result) Sar() []string {
+	res := make([]string, 0, len(r.Files))
+	for _, v := range r.Files {
+		res = append(res, v.Path)
+	}
+	return res
+}
+
+type Finfo struct {
+	Name      string
+	Path      string // includes name, relative path to cwd
+	Vany      int64  // any numeric value, used for sorting
+	Mask      Mask   // file kind, see Kinds
+	Size      int64
+	ModTime   time.Time
+	IsDir     bool
+	IsArchive bool // is a readable archive; ziplike
+
+	Archive string  // path of the archive this file is an entry of, if any
+	Entry   string  // name of the entry within Archive
+	Lines   []int   // line numbers matched by --contains
+	Group   int     // duplicate group, see DupesProcess
+	Hash    string  // hex digest, see HashProcess
+	Count   int     // number of files beneath a directory, see DiskUsage
+	Total   int64   // size of the files beneath a directory, see DiskUsage
+	Score   float32 // query score, see QueryProcess
+
+	Info fs.FileInfo // stat data found while traversing, nil for arguments and --file elements
+
+	zip *zipEntry // where the data of an archive entry is, if it was found while traversing
+}
+
+// DiskSize returns the total size of the files beneath a directory with --du, or the size of the file.
+func (fi *Finfo) DiskSize(opts *Options) int64 {
+	if fi.IsDir && opts.DiskUsage {
+		return fi.Total
+	}
+	return fi.Size
+}
+
+// Open opens the content of the file, reading from its archive if it is an entry of one.
+func (fi *Finfo) Open() (io.ReadCloser, error) {
+	switch {
+	case fi.Archive == "":
+		return os.Open(fi.Path)
+	case fi.zip != nil && (fi.zip.Method == zip.Store || fi.zip.Method == zip.Deflate):
+		return fi.zip.open(fi.Archive)
+	}
+
+	r, err := zip.OpenReader(fi.Archive)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.File {
+		if f.Name != fi.Entry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return &entryReader{rc, r}, nil
+	}
+	r.Close()
+	return nil, fmt.Errorf("entry %q not found in %q", fi.Entry, fi.Archive)
+}
+
+// entryReader closes the archive along with the entry.
+type entryReader struct {
+	io.ReadCloser
+	archive io.Closer
+}
+
+func (e *entryReader) Close() error {
+	err := e.ReadCloser.Close()
+	e.archive.Close()
+	return err
+}
+
+// zipEntry locates the data of an archive entry, so it can be read without reading the directory of the archive again.
+type zipEntry struct {
+	*zip.File
+	offset int64
+}
+
+func (e *zipEntry) open(archive string) (io.ReadCloser, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	data := io.NewSectionReader(f, e.offset, int64(e.CompressedSize64))
+	if e.Method == zip.Deflate {
+		return &entryReader{flate.NewReader(data), f}, nil
+	}
+	return &entryReader{io.NopCloser(data), f}, nil
+}
+
+// zipInfo is the fs.FileInfo of an archive entry.
+type zipInfo struct {
+	fs.FileInfo
+	archive string
+	entry   string
+	zip     *zipEntry
+}
+
+type ResultFilters func(*Finfo)
+
+type FinfoParser func(string, fs.FileInfo) *Finfo
+
+func InitFileParser(opts *Options) FinfoParser {
+	return func(path string, info fs.FileInfo) *Finfo {
+		fi := &Finfo{
+			Name:    info.Name(),
+			Path:    path,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+			Info:    info,
+		}
+
+		if zi, ok := info.(zipInfo); ok {
+			fi.Archive = zi.archive
+			fi.Entry = zi.entry
+			fi.zip = zi.zip
+		}
+
+		fi.Mask = ExtMask(fi.Name)
+		if opts.Sniff && !fi.IsDir {
+			fi.Mask = SniffMask(fi)
+		}
+
+		if fi.Mask.Has(MaskZipLike) {
+			fi.IsArchive = true
+		}
+
+		switch StrToSortBy(opts.Sort) {
+		case ByMod:
+			addModT(fi, info)
+		case BySize:
+			addSize(fi, info)
+		case ByCreation:
+			addCreationT(fi, info)
+		}
+
+		return fi
+	}
+}
+func addModT(fi *Finfo, info fs.FileInfo) { fi.Vany = info.ModTime().Unix() }
+func addSize(fi *Finfo, info fs.FileInfo) { fi.Vany = info.Size() }
+
+type Traverser func(*Options, ResultFilters)
+
+func GetTraverser(opts *Options) Traverser {
+	switch {
+	case opts.ArgMode:
+		return TraverseArgs
+	case opts.FileMode != "":
+		return FileTraverser
+	default:
+		return TraverseFS
+	}
+}
+
+func FileTraverser(opts *Options, rfn ResultFilters) {
+	for _, arg := range opts.Args {
+		b, err := os.ReadFile(arg)
+		if err != nil {
+			slog.Error("error reading file", "arg", arg, "error", err)
+			continue
+		}
+
+		var res []string
+
+		switch {
+		case opts.NullInput:
+			res = strings.Split(strings.TrimSuffix(string(b), "\x00"), "\x00")
+		case opts.FileMode == "words" || opts.FileMode == "w":
+			res = strings.Fields(string(b))
+		default:
+			res = strings.Split(string(b), "\n")
+		}
+
+		for _, line := range res {
+			rfn(StringParser(line))
+		}
+	}
+}
+
+func TraverseArgs(opts *Options, rfn ResultFilters) {
+	for _, arg := range opts.Args {
+		rfn(StringParser(arg))
+	}
+}
+
+func StringParser(s string) *Finfo {
+	return &Finfo{
+		Name: s,
+		Path: s,
+		Mask: ExtMask(s), // attempt, not guaranteed to be filepath
+	}
+}
+
+// TraverseFS traverses directories non-recursively and breadth first.
+func TraverseFS(opts *Options, rfn ResultFilters) {
+	var searchFn = func(string) bool { return true }
+	if len(opts.DirSearch) != 0 {
+		// the options are not changed, as they are traversed again by --watch
+		search := append(slices.Clip(opts.DirSearch), "./")
+		searchFn = func(str string) bool {
+			for _, k := range search {
+				if strings.Contains(str, k) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	parser := InitFileParser(opts)
+
+	dirs := opts.Args
+
+	if len(dirs) == 0 {
+		dirs = append(dirs, "./")
+	}
+
+	// with --du, directories below ToDepth are traversed for their sizes, but not included
+	var du *DiskUsage
+	if opts.DiskUsage {
+		du = NewDiskUsage(dirs)
+	}
+
+	var ix *Indexed
+	if opts.UseIndex {
+		ix = OpenIndexed(dirs, opts)
+		defer ix.Close()
+	}
+
+	// archives found while traversing, which may be zip archives only by their content
+	zips := map[string]bool{}
+
+	var depth int
+	for len(dirs) != 0 {
+		if depth > opts.ToDepth && du == nil {
+			break
+		}
+		var nd []string
+		for _, d := range dirs {
+			ext := filepath.Ext(d)
+			isZip := zips[d] || ExtMask(d).Has(MaskZipLike)
+			slog.Debug("traversing", "dir", d, "depth", depth, "ext", ext, "isarchive", isZip)
+
+			var files []fs.FileInfo
+
+			switch {
+			case opts.Archive && isZip && searchFn(d):
+				files = TraverseZip(d, depth, opts)
+			default:
+				files = ix.ReadDir(d, depth, opts)
+			}
+
+			for i, info := range files {
+				if i > opts.MaxLimit {
+					break
+				}
+				name := info.Name()
+				path := filepath.Join(d, name)
+				zi, isEntry := info.(zipInfo)
+				if isEntry {
+					path = filepath.Join(d, zi.entry)
+				}
+				if !opts.NoHide {
+					if _, ok := Hide[name]; ok || name[0] == '.' {
+						continue
+					}
+				}
+
+				if info.IsDir() && searchFn(name) {
+					nd = append(nd, path)
+					du.Dir(d, path)
+				}
+				if !info.IsDir() && !isEntry {
+					du.File(d, info.Size())
+				}
+
+				if opts.Archive && !isEntry && !info.IsDir() && IsZip(path, opts) {
+					zips[path] = true
+					nd = append(nd, path)
+					continue
+				}
+
+				if depth < opts.FromDepth || depth > opts.ToDepth {
+					continue
+				}
+
+				fi := parser(path, info)
+				du.Attach(path, fi)
+				rfn(fi)
+			}
+		}
+
+		dirs = nd
+		depth++
+	}
+
+	du.Total(StrToSortBy(opts.Sort) == BySize)
+}
+
+func TraverseDir(path string, depth int, opts *Options) (files []fs.FileInfo) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			slog.Error("error reading file info", "file", entry.Name(), "error", err)
+			continue
+		}
+		switch {
+		case opts.DirOnly && info.IsDir():
+			files = append(files, info)
+		case opts.FileOnly && !info.IsDir():
+			files = append(files, info)
+		default:
+			files = append(files, info)
+		}
+
+	}
+	return
+}
+
+func TraverseZip(path string, depth int, opts *Options) (files []fs.FileInfo) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		fn := filepath.ToSlash(f.Name)
+
+		fdepth := depth + strings.Count(fn, "/")
+		if fdepth < opts.FromDepth || fdepth > opts.ToDepth {
+			continue
+		}
+		info := f.FileInfo()
+		if info.IsDir() {
+			continue
+		}
+
+		zi := zipInfo{info, path, f.Name, nil}
+		if offset, err := f.DataOffset(); err == nil {
+			zi.zip = &zipEntry{f, offset}
+		}
+		files = append(files, zi)
+	}
+
+	return
+}
+
+func InitFilters(fns []Filter, res *Result) ResultFilters {
+	return func(fi *Finfo) {
+		for _, fn := range fns {
+			if !fn(fi) {
+				return
+			}
+		}
+		res.Files = append(res.Files, fi)
+	}
+}
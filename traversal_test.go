@@ -0,0 +1,100 @@
+package list
+
+import (
+	"archive/zip"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeZip(t *testing.T, path string, entries map[string]string, method uint16) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range entries {
+		ew, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(ew, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenArchiveEntry(t *testing.T) {
+	entries := map[string]string{
+		"a.txt":       "first",
+		"dir/b.txt":   "second entry",
+		"dir/c/d.txt": "",
+	}
+	for _, method := range []uint16{zip.Store, zip.Deflate} {
+		path := filepath.Join(t.TempDir(), "test.zip")
+		writeZip(t, path, entries, method)
+
+		opts := &Options{}
+		opts.ToDepth = math.MaxInt64
+		parser := InitFileParser(opts)
+		files := TraverseZip(path, 0, opts)
+		if len(files) != len(entries) {
+			t.Fatalf("method %d: got %d entries, want %d", method, len(files), len(entries))
+		}
+		for _, info := range files {
+			zi := info.(zipInfo)
+			fi := parser(filepath.Join(path, zi.entry), info)
+			if fi.zip == nil {
+				t.Errorf("method %d: %s: entry was not located", method, zi.entry)
+			}
+			rc, err := fi.Open()
+			if err != nil {
+				t.Fatalf("method %d: %s: %v", method, zi.entry, err)
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("method %d: %s: %v", method, zi.entry, err)
+			}
+			if string(b) != entries[zi.entry] {
+				t.Errorf("method %d: %s: got %q, want %q", method, zi.entry, b, entries[zi.entry])
+			}
+		}
+	}
+}
+
+func TestTraverseTwice(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"sub/a": "", "other/b": "", "c": ""})
+
+	opts := &Options{Args: []string{dir}}
+	opts.MaxLimit = math.MaxInt64
+	opts.ToDepth = math.MaxInt64
+	opts.DirSearch = []string{"sub"}
+
+	// --watch runs the same options again for every change
+	var runs [2][]string
+	for i := range runs {
+		for _, fi := range Run(opts).Files {
+			runs[i] = append(runs[i], fi.Name)
+		}
+		slices.Sort(runs[i])
+	}
+	if !slices.Equal(runs[0], runs[1]) {
+		t.Errorf("the second run found %v, the first %v", runs[1], runs[0])
+	}
+	if slices.Contains(runs[0], "b") {
+		t.Errorf("found %v, b is not beneath a searched directory", runs[0])
+	}
+	if len(opts.DirSearch) != 1 {
+		t.Errorf("--dirsearch was changed to %v", opts.DirSearch)
+	}
+}
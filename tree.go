@@ -0,0 +1,236 @@
+package list
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type TreeNode struct {
+	name     string
+	fi       *Finfo // nil for directories which are not in the result
+	children map[string]*TreeNode
+	order    []string // names of the children in the order they were added
+}
+
+func NewTreeNode(name string) *TreeNode {
+	return &TreeNode{name: name, children: make(map[string]*TreeNode)}
+}
+
+// AddPath adds the nodes of the slash separated path, returning the last one.
+func (t *TreeNode) AddPath(path string) *TreeNode {
+	current := t
+	for _, part := range strings.Split(path, "/") {
+		if part == "" {
+			continue
+		}
+		if _, exists := current.children[part]; !exists {
+			current.children[part] = NewTreeNode(part)
+			current.order = append(current.order, part)
+		}
+		current = current.children[part]
+	}
+	return current
+}
+
+// Collapse merges directories which only contain a single directory into it, e.g., `a/b/c`.
+// The merged node keeps the file of the deepest directory, or of the deepest one in the result.
+func (t *TreeNode) Collapse() {
+	for _, key := range t.order {
+		child := t.children[key]
+		for len(child.children) == 1 {
+			only := child.children[child.order[0]]
+			if len(only.children) == 0 {
+				break
+			}
+			only.name = child.name + "/" + only.name
+			if only.fi == nil {
+				only.fi = child.fi
+			}
+			child = only
+		}
+		t.children[key] = child
+		child.Collapse()
+	}
+}
+
+// treeRoots returns the roots the files are printed under: the traversed directories, or the working directory.
+func treeRoots(opts *Options) []string {
+	roots := []string{"."}
+	if !opts.ArgMode && opts.FileMode == "" && len(opts.Args) != 0 {
+		roots = opts.Args
+	}
+
+	res := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if opts.Absolute {
+			root, _ = filepath.Abs(root)
+		}
+		res = append(res, filepath.ToSlash(filepath.Clean(root)))
+	}
+	return res
+}
+
+// BuildTree places the files under the root they are found in. Files which are under none
+// of the roots are placed under `/` if their path is absolute, and under `.` otherwise.
+func BuildTree(files []*Finfo, opts *Options) []*TreeNode {
+	var roots []*TreeNode
+	byName := map[string]*TreeNode{}
+	getRoot := func(name string) *TreeNode {
+		if root, ok := byName[name]; ok {
+			return root
+		}
+		root := NewTreeNode(name)
+		byName[name] = root
+		roots = append(roots, root)
+		return root
+	}
+
+	names := treeRoots(opts)
+	// longest roots first, so that nested roots take their own files
+	slices.SortStableFunc(names, func(a, b string) int { return len(b) - len(a) })
+
+	for _, file := range files {
+		fp := FormatPath(file, opts)
+
+		var root *TreeNode
+		for _, name := range names {
+			if rel, ok := underRoot(fp, name); ok {
+				root, fp = getRoot(name), rel
+				break
+			}
+		}
+		if root == nil {
+			switch {
+			case strings.HasPrefix(fp, "/"):
+				root = getRoot("/")
+			default:
+				root = getRoot(".")
+			}
+		}
+
+		root.AddPath(fp).fi = file
+	}
+
+	if opts.Collapse {
+		for _, root := range roots {
+			root.Collapse()
+		}
+	}
+	return roots
+}
+
+// underRoot returns the path relative to root, reporting whether the path is beneath it.
+func underRoot(fp, root string) (string, bool) {
+	switch {
+	case root == ".":
+		return fp, !filepath.IsAbs(fp) && fp != ".." && !strings.HasPrefix(fp, "../")
+	case root == "/":
+		return fp, strings.HasPrefix(fp, "/")
+	case strings.HasPrefix(fp, root+"/"):
+		return fp[len(root)+1:], true
+	}
+	return "", false
+}
+
+// treeColumns returns the columns printed before the names of the files.
+func treeColumns(opts *Options) []string {
+	if len(opts.TreeInfo) == 0 && opts.DiskUsage {
+		return []string{"size", "count"}
+	}
+	return opts.TreeInfo
+}
+
+type treePrinter struct {
+	w       *bufio.Writer
+	opts    *Options
+	colors  *Colors
+	columns []string
+	widths  []int
+}
+
+func (p *treePrinter) cells(fi *Finfo) []string {
+	res := make([]string, len(p.columns))
+	if fi == nil {
+		return res
+	}
+	for i, col := range p.columns {
+		switch col {
+		case "size":
+			res[i] = FormatSize(fi.DiskSize(p.opts), p.opts)
+		case "count":
+			if fi.IsDir {
+				res[i] = strconv.Itoa(fi.Count)
+			}
+		case "date":
+			if !fi.ModTime.IsZero() {
+				res[i] = fi.ModTime.Format("2006-01-02 15:04")
+			}
+		}
+	}
+	return res
+}
+
+// measure sets the width of each column to the widest cell displayed.
+func (p *treePrinter) measure(t *TreeNode, depth int) {
+	if p.opts.TreeDepth > 0 && depth > p.opts.TreeDepth {
+		return
+	}
+	for i, cell := range p.cells(t.fi) {
+		p.widths[i] = max(p.widths[i], len(cell))
+	}
+	for _, key := range t.order {
+		p.measure(t.children[key], depth+1)
+	}
+}
+
+func (p *treePrinter) line(prefix string, t *TreeNode) {
+	if len(p.columns) > 0 {
+		p.w.WriteString("[")
+		for i, cell := range p.cells(t.fi) {
+			if i > 0 {
+				p.w.WriteString(" ")
+			}
+			fmt.Fprintf(p.w, "%*s", p.widths[i], cell)
+		}
+		p.w.WriteString("] ")
+	}
+	fi := t.fi
+	if fi == nil {
+		fi = &Finfo{Name: t.name, IsDir: true}
+	}
+	p.w.WriteString(prefix + p.colors.Paint(t.name, fi) + "\n")
+}
+
+func (p *treePrinter) print(t *TreeNode, prefix string, depth int) {
+	if p.opts.TreeDepth > 0 && depth >= p.opts.TreeDepth {
+		return
+	}
+	for i, key := range t.order {
+		child := t.children[key]
+		if i == len(t.order)-1 {
+			p.line(prefix+"└── ", child)
+			p.print(child, prefix+"    ", depth+1)
+		} else {
+			p.line(prefix+"├── ", child)
+			p.print(child, prefix+"│   ", depth+1)
+		}
+	}
+}
+
+// PrintTree writes each root followed by the files beneath it.
+func PrintTree(w *bufio.Writer, roots []*TreeNode, opts *Options) {
+	p := &treePrinter{w: w, opts: opts, colors: NewColors(opts), columns: treeColumns(opts)}
+	p.widths = make([]int, len(p.columns))
+	for _, root := range roots {
+		p.measure(root, 0)
+	}
+
+	for _, root := range roots {
+		p.line("", root)
+		p.print(root, "", 0)
+	}
+}
@@ -0,0 +1,48 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestCollapseKeepsDeepestFile(t *testing.T) {
+	a := &Finfo{Name: "a", Path: "a", IsDir: true, Count: 1}
+	c := &Finfo{Name: "c", Path: "a/b/c", IsDir: true, Count: 3}
+	x := &Finfo{Name: "x", Path: "a/b/c/x"}
+	y := &Finfo{Name: "y", Path: "d/e/y"}
+	d := &Finfo{Name: "d", Path: "d", IsDir: true, Count: 2}
+
+	opts := &Options{}
+	opts.Collapse = true
+	roots := BuildTree([]*Finfo{a, c, x, d, y}, opts)
+	if len(roots) != 1 {
+		t.Fatalf("got %d roots, want 1", len(roots))
+	}
+
+	tests := []struct {
+		name string
+		fi   *Finfo
+	}{
+		{"a/b/c", c},
+		{"d/e", d},
+	}
+	root := roots[0]
+	for i, tt := range tests {
+		node := root.children[root.order[i]]
+		if node.name != tt.name || node.fi != tt.fi {
+			t.Errorf("node %d: got %s %v, want %s %v", i, node.name, node.fi, tt.name, tt.fi)
+		}
+	}
+
+	opts.Color = "never"
+	opts.TreeInfo = []string{"count"}
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	PrintTree(w, roots, opts)
+	w.Flush()
+	want := "[ ] .\n[3] ├── a/b/c\n[ ] │   └── x\n[2] └── d/e\n[ ]     └── y\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package list
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoTerminal = errors.New("the terminal is not supported on this platform")
+
+func rawMode(_ *os.File) (restore func(), err error) { return nil, errNoTerminal }
+
+func termSize(_ *os.File) (width, height int, err error) { return 0, 0, errNoTerminal }
+
+func notifyResize(_ chan<- os.Signal) {}
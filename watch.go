@@ -0,0 +1,272 @@
+package list
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Changes reported by --watch events.
+const (
+	EventCreate = "create"
+	EventModify = "modify"
+	EventDelete = "delete"
+)
+
+// WatchEvent is a change of a file in a watched directory.
+type WatchEvent struct {
+	Op    string
+	Path  string
+	IsDir bool
+}
+
+// dirWatcher reports the changes of the files directly in the directories added to it.
+type dirWatcher interface {
+	Add(dir string) error
+	Events() <-chan WatchEvent
+	Close() error
+}
+
+// watchRecord is the form of an event printed by --format ndjson.
+type watchRecord struct {
+	Event string `json:"event"`
+	Record
+}
+
+// Watch watches the traversed directories to --todepth, skipping hidden and ignored ones, and runs output
+// with the result every time it changes, or prints the changes themselves with --watch events.
+// Changes are collected until none happen for --watch-delay. Watch returns only when watching fails.
+func Watch(opts *Options, output func(*Result) int) int {
+	if opts.ArgMode || opts.FileMode != "" {
+		fmt.Fprintln(os.Stderr, "--watch can only be used on directories")
+		return 1
+	}
+
+	dw, err := newDirWatcher()
+	if err != nil {
+		slog.Error("error watching", "error", err)
+		return 1
+	}
+	defer dw.Close()
+
+	w := &watch{
+		opts:    opts,
+		dw:      dw,
+		depths:  map[string]int{},
+		parser:  InitFileParser(opts),
+		filters: CollectFilters(opts),
+		pending: map[string]string{},
+		out:     bufio.NewWriter(os.Stdout),
+	}
+
+	roots := opts.Args
+	if len(roots) == 0 {
+		roots = []string{"./"}
+	}
+	for _, root := range roots {
+		w.addTree(filepath.Clean(root), 0, false)
+	}
+
+	if opts.Watch == "print" {
+		w.print(output)
+	}
+
+	timer := time.NewTimer(opts.WatchDelay)
+	timer.Stop()
+	for {
+		select {
+		case ev, ok := <-dw.Events():
+			if !ok {
+				return 1
+			}
+			w.handle(ev)
+			timer.Reset(opts.WatchDelay)
+		case <-timer.C:
+			switch {
+			case len(w.order) == 0:
+			case opts.Watch == "print":
+				w.print(output)
+			default:
+				w.emit()
+			}
+			w.order, w.pending = nil, map[string]string{}
+		}
+	}
+}
+
+type watch struct {
+	opts    *Options
+	dw      dirWatcher
+	depths  map[string]int // depth of the files in each watched directory
+	parser  FinfoParser
+	filters []Filter
+
+	pending map[string]string // coalesced change of each path since the last output
+	order   []string
+	out     *bufio.Writer
+}
+
+// addTree watches dir, whose files are at depth, and the directories beneath it to --todepth.
+// With report, the files found are reported as created, as they may have been created before the watch.
+func (w *watch) addTree(dir string, depth int, report bool) {
+	if depth > w.opts.ToDepth {
+		return
+	}
+	if err := w.dw.Add(dir); err != nil {
+		slog.Error("error watching directory", "dir", dir, "error", err)
+		return
+	}
+	w.depths[dir] = depth
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		slog.Error("error reading directory", "dir", dir, "error", err)
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if w.skip(path) {
+			continue
+		}
+		if report {
+			w.add(EventCreate, path)
+		}
+		if entry.IsDir() && w.descend(entry.Name()) {
+			w.addTree(path, depth+1, report)
+		}
+	}
+}
+
+// skip reports whether the file is hidden like in traversal.
+func (w *watch) skip(path string) bool {
+	if w.opts.NoHide {
+		return false
+	}
+	name := filepath.Base(path)
+	_, ok := Hide[name]
+	return ok || name[0] == '.'
+}
+
+// descend reports whether the directory is traversed, i.e., it matches --dirsearch and is not ignored.
+func (w *watch) descend(name string) bool {
+	for _, ign := range w.opts.Ignore {
+		if strings.Contains(name, ign) {
+			return false
+		}
+	}
+	if len(w.opts.DirSearch) == 0 {
+		return true
+	}
+	for _, k := range w.opts.DirSearch {
+		if strings.Contains(name, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *watch) handle(ev WatchEvent) {
+	dir := filepath.Dir(ev.Path)
+	if ev.Path == dir || w.skip(ev.Path) {
+		return
+	}
+	depth := w.depths[dir]
+	if depth >= w.opts.FromDepth {
+		w.add(ev.Op, ev.Path)
+	}
+
+	switch {
+	case ev.Op == EventDelete:
+		delete(w.depths, ev.Path)
+	case ev.Op == EventCreate && ev.IsDir && w.descend(filepath.Base(ev.Path)):
+		w.addTree(ev.Path, depth+1, true)
+	}
+}
+
+// add coalesces the change with the pending change of the path: a file created and deleted
+// did not change, a file deleted and created was modified, and a created file stays created.
+func (w *watch) add(op, path string) {
+	prev, ok := w.pending[path]
+	switch {
+	case !ok:
+		w.order = append(w.order, path)
+	case prev == EventCreate && op == EventDelete:
+		op = ""
+	case prev == EventCreate:
+		op = EventCreate
+	case prev == EventDelete && op == EventCreate:
+		op = EventModify
+	}
+	w.pending[path] = op
+}
+
+// finfo returns the file of a change, parsed from its name if it no longer exists.
+func (w *watch) finfo(path string) *Finfo {
+	if info, err := os.Lstat(path); err == nil {
+		return w.parser(path, info)
+	}
+	return &Finfo{Name: filepath.Base(path), Path: path, Mask: ExtMask(path)}
+}
+
+func (w *watch) matches(fi *Finfo) bool {
+	for _, fn := range w.filters {
+		if !fn(fi) {
+			return false
+		}
+	}
+	return true
+}
+
+// print runs output with the result when a change matches the filters, clearing the terminal first.
+func (w *watch) print(output func(*Result) int) {
+	if len(w.order) > 0 {
+		changed := false
+		for _, path := range w.order {
+			if op := w.pending[path]; op != "" && w.matches(w.finfo(path)) {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Print("\x1b[H\x1b[2J")
+	}
+	output(Run(w.opts))
+}
+
+// emit prints the changes which match the filters, as `event<tab>path` lines or ndjson with --format ndjson.
+func (w *watch) emit() {
+	end := Terminator(w.opts)
+	for _, path := range w.order {
+		op := w.pending[path]
+		if op == "" {
+			continue
+		}
+		fi := w.finfo(path)
+		if !w.matches(fi) {
+			continue
+		}
+
+		if w.opts.Format == "ndjson" {
+			b, err := json.Marshal(watchRecord{op, NewRecord(fi)})
+			if err != nil {
+				slog.Error("error encoding event", "path", path, "error", err)
+				continue
+			}
+			w.out.Write(b)
+			w.out.WriteString("\n")
+			continue
+		}
+		w.out.WriteString(op + "\t" + FormatPath(fi, w.opts) + end)
+	}
+	w.out.Flush()
+}
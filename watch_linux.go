@@ -0,0 +1,103 @@
+//go:build linux
+// +build linux
+
+package list
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask are the changes watched in every directory.
+const inotifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ONLYDIR
+
+// inotify watches directories with the inotify API of Linux.
+type inotify struct {
+	fd     int
+	events chan WatchEvent
+
+	mu   sync.Mutex
+	dirs map[int32]string // watch descriptors to their directories
+}
+
+func newDirWatcher() (dirWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	w := &inotify{fd: fd, events: make(chan WatchEvent, 64), dirs: map[int32]string{}}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotify) Add(dir string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.dirs[int32(wd)] = dir
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *inotify) Events() <-chan WatchEvent { return w.events }
+
+func (w *inotify) Close() error { return unix.Close(w.fd) }
+
+// read sends the events read from inotify until it is closed. Renames are reported as a delete of the
+// old path and a create of the new one.
+func (w *inotify) read() {
+	defer close(w.events)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := unix.Read(w.fd, buf)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			return
+		}
+
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + unix.SizeofInotifyEvent
+			off = nameStart + int(raw.Len)
+			if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+				slog.Error("too many changes, some were not reported")
+				continue
+			}
+
+			w.mu.Lock()
+			dir, ok := w.dirs[raw.Wd]
+			if raw.Mask&unix.IN_IGNORED != 0 {
+				delete(w.dirs, raw.Wd)
+			}
+			w.mu.Unlock()
+			if !ok || raw.Len == 0 {
+				continue
+			}
+
+			name := string(buf[nameStart:min(off, n)])
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+
+			ev := WatchEvent{Path: filepath.Join(dir, name), IsDir: raw.Mask&unix.IN_ISDIR != 0}
+			switch {
+			case raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+				ev.Op = EventCreate
+			case raw.Mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+				ev.Op = EventDelete
+			default:
+				ev.Op = EventModify
+			}
+			w.events <- ev
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package list
+
+import "errors"
+
+func newDirWatcher() (dirWatcher, error) {
+	return nil, errors.New("--watch is only supported on linux")
+}
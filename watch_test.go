@@ -0,0 +1,155 @@
+package list
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeWatcher records the watched directories instead of watching them.
+type fakeWatcher struct{ dirs []string }
+
+func (f *fakeWatcher) Add(dir string) error      { f.dirs = append(f.dirs, dir); return nil }
+func (f *fakeWatcher) Events() <-chan WatchEvent { return nil }
+func (f *fakeWatcher) Close() error              { return nil }
+
+func newTestWatch(opts *Options) (*watch, *fakeWatcher) {
+	fw := &fakeWatcher{}
+	return &watch{opts: opts, dw: fw, depths: map[string]int{}, pending: map[string]string{}}, fw
+}
+
+// pendingRel returns the pending changes as `op path` relative to dir, in the order of their first change.
+func pendingRel(w *watch, dir string) (res []string) {
+	for _, path := range w.order {
+		rel, _ := filepath.Rel(dir, path)
+		res = append(res, w.pending[path]+" "+filepath.ToSlash(rel))
+	}
+	return
+}
+
+func TestWatchCoalesce(t *testing.T) {
+	tests := []struct {
+		ops  []string
+		want string
+	}{
+		{[]string{EventCreate}, EventCreate},
+		{[]string{EventCreate, EventDelete}, ""},
+		{[]string{EventDelete, EventCreate}, EventModify},
+		{[]string{EventCreate, EventModify}, EventCreate},
+		{[]string{EventModify, EventModify}, EventModify},
+		{[]string{EventModify, EventDelete}, EventDelete},
+		{[]string{EventCreate, EventDelete, EventCreate}, EventCreate},
+	}
+	for _, tt := range tests {
+		w, _ := newTestWatch(&Options{})
+		for _, op := range tt.ops {
+			w.add(op, "f")
+		}
+		if got := w.pending["f"]; got != tt.want || len(w.order) != 1 {
+			t.Errorf("%v: got %q in %v, want %q once", tt.ops, got, w.order, tt.want)
+		}
+	}
+}
+
+func TestWatchAddTree(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a/b/c/f": "", "a/g": "", ".hidden/h": "", "skip/i": "", "top": ""})
+
+	tests := []struct {
+		name    string
+		toDepth int
+		noHide  bool
+		ignore  []string
+		search  []string
+		want    []string // watched directories relative to dir
+	}{
+		{"all", 10, false, nil, nil, []string{".", "a", "a/b", "a/b/c", "skip"}},
+		{"todepth", 1, false, nil, nil, []string{".", "a", "skip"}},
+		{"depth 0", 0, true, nil, nil, []string{"."}},
+		{"hidden", 10, true, nil, nil, []string{".", ".hidden", "a", "a/b", "a/b/c", "skip"}},
+		{"ignore", 10, false, []string{"skip", "b"}, nil, []string{".", "a"}},
+		{"dirsearch", 10, false, nil, []string{"a", "c"}, []string{".", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{}
+			opts.ToDepth, opts.NoHide, opts.Ignore, opts.DirSearch = tt.toDepth, tt.noHide, tt.ignore, tt.search
+			w, fw := newTestWatch(opts)
+			w.addTree(dir, 0, false)
+
+			var got []string
+			for _, d := range fw.dirs {
+				rel, _ := filepath.Rel(dir, d)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("watched %v, want %v", got, tt.want)
+			}
+			if len(w.order) != 0 {
+				t.Errorf("changes reported without report: %v", pendingRel(w, dir))
+			}
+		})
+	}
+}
+
+func TestWatchHandle(t *testing.T) {
+	tests := []struct {
+		name      string
+		fromDepth int
+		toDepth   int
+		setup     map[string]string // files which exist when the events happen
+		events    []WatchEvent      // paths relative to the root
+		want      []string
+		watched   []string // directories watched after the events, relative to the root
+	}{
+		{"files", 0, 10, nil,
+			[]WatchEvent{{EventCreate, "x", false}, {EventModify, "a/f", false}, {EventCreate, ".x", false}},
+			[]string{"create x", "modify a/f"}, []string{".", "a"}},
+		{"fromdepth", 1, 10, nil,
+			[]WatchEvent{{EventCreate, "x", false}, {EventModify, "a/f", false}},
+			[]string{"modify a/f"}, []string{".", "a"}},
+		{"new directory", 0, 10, map[string]string{"n/m/g": ""},
+			[]WatchEvent{{EventCreate, "n", true}},
+			[]string{"create n", "create n/m", "create n/m/g"}, []string{".", "a", "n", "n/m"}},
+		{"new directory below todepth", 0, 1, map[string]string{"a/n/g": ""},
+			[]WatchEvent{{EventCreate, "a/n", true}},
+			[]string{"create a/n"}, []string{".", "a"}},
+		{"new hidden directory", 0, 10, map[string]string{".n/g": ""},
+			[]WatchEvent{{EventCreate, ".n", true}},
+			nil, []string{".", "a"}},
+		{"deleted directory", 0, 10, nil,
+			[]WatchEvent{{EventDelete, "a", true}, {EventCreate, "a", false}},
+			[]string{"modify a"}, []string{"."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{"a/f": ""})
+			opts := &Options{}
+			opts.FromDepth, opts.ToDepth = tt.fromDepth, tt.toDepth
+			w, _ := newTestWatch(opts)
+			w.addTree(root, 0, false)
+
+			writeFiles(t, root, tt.setup)
+			for _, ev := range tt.events {
+				ev.Path = filepath.Join(root, filepath.FromSlash(ev.Path))
+				w.handle(ev)
+			}
+
+			if got := pendingRel(w, root); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got changes %v, want %v", got, tt.want)
+			}
+			var watched []string
+			for d := range w.depths {
+				rel, _ := filepath.Rel(root, d)
+				watched = append(watched, filepath.ToSlash(rel))
+			}
+			slices.Sort(watched)
+			if !slices.Equal(watched, tt.watched) {
+				t.Errorf("watching %v, want %v", watched, tt.watched)
+			}
+		})
+	}
+}